@@ -0,0 +1,135 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// commandTimeout is how long an alert command may run before it's killed.
+const commandTimeout = 30 * time.Second
+
+// Alert states, as passed to actions
+const (
+	Firing   = "firing"
+	Resolved = "resolved"
+)
+
+// Payload is the JSON body POSTed to webhooks. The same details are passed to
+// commands in GOTOP_ALERT_* environment variables.
+type Payload struct {
+	Alert     string    `json:"alert"`
+	State     string    `json:"state"`
+	Host      string    `json:"host"`
+	Metric    string    `json:"metric"`
+	Domain    string    `json:"domain"`
+	Key       string    `json:"key"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Rule      string    `json:"rule"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"time"`
+	// Since is when the alert fired
+	Since time.Time `json:"since"`
+}
+
+// payload describes the event.
+func (e *Engine) payload(ev Event) Payload {
+	p := Payload{
+		Alert:     ev.Rule.Name,
+		State:     Firing,
+		Host:      e.host,
+		Metric:    ev.Metric(),
+		Domain:    ev.Rule.Domain,
+		Key:       ev.Key,
+		Value:     ev.Value,
+		Threshold: ev.Rule.Threshold,
+		Rule:      ev.Rule.String(),
+		Message:   ev.Message,
+		Time:      ev.Time.UTC(),
+		Since:     ev.Since.UTC(),
+	}
+	if ev.Resolved {
+		p.State = Resolved
+	}
+	return p
+}
+
+// Notify runs the command, and posts to the webhook, of the event's rule.
+func (e *Engine) Notify(ev Event) []error {
+	p := e.payload(ev)
+	var errs []error
+	if ev.Rule.Command != "" {
+		if err := runCommand(ev.Rule.Command, p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if ev.Rule.Webhook != "" {
+		if err := e.post(ev.Rule.Webhook, p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// runCommand runs the command with the shell, adding the payload to its
+// environment.
+func runCommand(command string, p Payload) error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(),
+		"GOTOP_ALERT="+p.Alert,
+		"GOTOP_ALERT_STATE="+p.State,
+		"GOTOP_ALERT_HOST="+p.Host,
+		"GOTOP_ALERT_METRIC="+p.Metric,
+		"GOTOP_ALERT_DOMAIN="+p.Domain,
+		"GOTOP_ALERT_KEY="+p.Key,
+		"GOTOP_ALERT_VALUE="+strconv.FormatFloat(p.Value, 'f', -1, 64),
+		"GOTOP_ALERT_THRESHOLD="+strconv.FormatFloat(p.Threshold, 'f', -1, 64),
+		"GOTOP_ALERT_RULE="+p.Rule,
+		"GOTOP_ALERT_MESSAGE="+p.Message,
+		"GOTOP_ALERT_TIME="+p.Time.Format(time.RFC3339),
+		"GOTOP_ALERT_SINCE="+p.Since.Format(time.RFC3339),
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %s: %s", command, err, msg)
+		}
+		return fmt.Errorf("%s: %s", command, err)
+	}
+	return nil
+}
+
+// post sends the payload to the webhook.
+func (e *Engine) post(url string, p Payload) error {
+	bs, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	res, err := e.client.Post(url, "application/json", bytes.NewReader(bs))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s: %s", url, res.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
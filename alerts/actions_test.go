@@ -0,0 +1,117 @@
+package alerts
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xxxserxxx/lingo/v2"
+)
+
+func TestNotify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test command is a shell script")
+	}
+	var got []Payload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p Payload
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+		got = append(got, p)
+	}))
+	defer ts.Close()
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	script := filepath.Join(dir, "notify.sh")
+	assert.NoError(t, ioutil.WriteFile(script, []byte(`#!/bin/sh
+echo "$GOTOP_ALERT $GOTOP_ALERT_STATE $GOTOP_ALERT_METRIC $GOTOP_ALERT_VALUE $GOTOP_ALERT_THRESHOLD" >> "$1"
+`), 0755))
+
+	r, _ := ParseRule("hot", "temp.* > 85")
+	r.Command, r.Webhook = script+" "+out, ts.URL
+	e := NewEngine([]Rule{r}, lingo.Translations{})
+	start := time.Unix(1600000000, 0)
+	fired := Event{Alert: Alert{Rule: r, Key: "acpi", Value: 90.5, Since: start}, Time: start, Message: "hot"}
+	resolved := fired
+	resolved.Resolved, resolved.Value, resolved.Time = true, 80, start.Add(time.Minute)
+	for _, ev := range []Event{fired, resolved} {
+		assert.Empty(t, e.Notify(ev))
+	}
+
+	bs, err := ioutil.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, "hot firing temp.acpi 90.5 85\nhot resolved temp.acpi 80 85\n", string(bs))
+	host, _ := os.Hostname()
+	assert.Equal(t, []Payload{
+		{Alert: "hot", State: Firing, Host: host, Metric: "temp.acpi", Domain: "temp", Key: "acpi", Value: 90.5, Threshold: 85,
+			Rule: "temp.* > 85", Message: "hot", Time: start.UTC(), Since: start.UTC()},
+		{Alert: "hot", State: Resolved, Host: host, Metric: "temp.acpi", Domain: "temp", Key: "acpi", Value: 80, Threshold: 85,
+			Rule: "temp.* > 85", Message: "hot", Time: start.Add(time.Minute).UTC(), Since: start.UTC()},
+	}, got)
+
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	})
+	r.Command = "echo failed >&2; exit 3"
+	fired.Rule = r
+	errs := e.Notify(fired)
+	if assert.Len(t, errs, 2) {
+		assert.True(t, strings.HasSuffix(errs[0].Error(), "failed"), errs[0].Error())
+		assert.Contains(t, errs[1].Error(), "403")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	r, _ := ParseRule("hot", "temp.* > 85")
+	r.Command, r.RateLimit = "true", 15*time.Second
+	quiet := r
+	quiet.Name, quiet.Resolved = "quiet", false
+	e := NewEngine([]Rule{r, quiet}, lingo.Translations{})
+	var got []string
+	e.notify = func(ev Event) {
+		state := Firing
+		if ev.Resolved {
+			state = Resolved
+		}
+		got = append(got, ev.Rule.Name+" "+ev.Key+" "+state)
+	}
+	start := time.Unix(1600000000, 0)
+	steps := []map[string]float64{
+		{"a": 90, "b": 90},
+		{"a": 80, "b": 90},
+		{"a": 90, "b": 80},
+		{"a": 80},
+	}
+	for i, s := range steps {
+		e.Evaluate(start.Add(time.Duration(i)*5*time.Second), map[string]map[string]float64{"temp": s})
+	}
+	// Both rules fire for a at 0s; b's firing is limited, as is a's at 10s.
+	// Only hot notifies resolutions, and only those of notified firings.
+	assert.Equal(t, []string{"hot a firing", "quiet a firing", "hot a resolved"}, got)
+}
+
+func TestQuiet(t *testing.T) {
+	r, _ := ParseRule("hot", "temp.* > 85")
+	r.Command, r.Webhook = "true", "http://localhost"
+	e := NewEngine([]Rule{r}, lingo.Translations{})
+	e.Quiet = true
+	var got []Event
+	e.notify = func(ev Event) {
+		got = append(got, ev)
+	}
+	start := time.Unix(1600000000, 0)
+	evs := e.Evaluate(start, map[string]map[string]float64{"temp": {"a": 90}})
+	assert.Len(t, evs, 1)
+	assert.Len(t, e.Firing(), 1)
+	evs = e.Evaluate(start.Add(time.Second), map[string]map[string]float64{"temp": {"a": 80}})
+	assert.Len(t, evs, 1)
+	assert.Empty(t, got)
+}
@@ -0,0 +1,359 @@
+// Package alerts evaluates threshold rules, such as `cpu.avg > 90 for 30s`,
+// against the values displayed by the widgets.
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xxxserxxx/lingo/v2"
+
+	"github.com/xxxserxxx/gotop/v4/utils"
+)
+
+// PREFIX starts the config keys of alerts, which are alert-NAME-OPTION, e.g.
+// alert-hot-rule=temp.* > 85
+const PREFIX = "alert-"
+
+// maxEvents is the number of events kept for the alert log.
+const maxEvents = 1000
+
+var ops = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// Rule is a threshold condition on the values of a widget.
+type Rule struct {
+	Name string
+	// Domain is the widget the values come from, as named in layouts, e.g. cpu
+	Domain string
+	// Key is a pattern matched against the widget's device names with
+	// utils.Glob; * matches any text, and ? any single character. Matching
+	// ignores case.
+	Key       string
+	Op        string
+	Threshold float64
+	// For is how long the condition must hold before the alert fires.
+	For time.Duration
+	// Command is run by the shell when the alert fires or resolves
+	Command string
+	// Webhook is a URL that's sent a JSON Payload when the alert fires or
+	// resolves
+	Webhook string
+	// RateLimit is the minimum time between firing notifications of the rule;
+	// firings in between aren't notified, nor are their resolutions.
+	RateLimit time.Duration
+	// Resolved is true if actions are also run when alerts resolve
+	Resolved bool
+	text     string
+}
+
+// ParseRule parses a rule of the form `DOMAIN.KEY OP VALUE [for DURATION]`,
+// e.g. `disk./ used > 90 for 1m`. The key may contain spaces.
+func ParseRule(name, s string) (Rule, error) {
+	r := Rule{Name: name, text: strings.TrimSpace(s), RateLimit: time.Minute, Resolved: true}
+	fs := strings.Fields(s)
+	if len(fs) >= 2 && strings.ToLower(fs[len(fs)-2]) == "for" {
+		d, err := time.ParseDuration(fs[len(fs)-1])
+		if err != nil {
+			return r, err
+		}
+		r.For = d
+		fs = fs[:len(fs)-2]
+	}
+	if len(fs) < 3 {
+		return r, errors.New("missing metric, operator, or value")
+	}
+	v, err := strconv.ParseFloat(fs[len(fs)-1], 64)
+	if err != nil {
+		return r, err
+	}
+	r.Threshold = v
+	r.Op = fs[len(fs)-2]
+	valid := false
+	for _, o := range ops {
+		valid = valid || r.Op == o
+	}
+	if !valid {
+		return r, fmt.Errorf("unknown operator %s", r.Op)
+	}
+	metric := strings.Join(fs[:len(fs)-2], " ")
+	i := strings.Index(metric, ".")
+	if i < 1 || i == len(metric)-1 {
+		return r, fmt.Errorf("metric %s must be DOMAIN.KEY", metric)
+	}
+	r.Domain, r.Key = strings.ToLower(metric[:i]), metric[i+1:]
+	return r, nil
+}
+
+// String returns the rule as it was configured.
+func (r Rule) String() string {
+	return r.text
+}
+
+// holds reports whether v meets the rule's condition.
+func (r Rule) holds(v float64) bool {
+	switch r.Op {
+	case ">":
+		return v > r.Threshold
+	case "<":
+		return v < r.Threshold
+	case ">=":
+		return v >= r.Threshold
+	case "<=":
+		return v <= r.Threshold
+	case "==":
+		return v == r.Threshold
+	default:
+		return v != r.Threshold
+	}
+}
+
+// Rules reads the alert-NAME-rule keys of the config's extension variables,
+// and the rules' alert-NAME-command, -webhook, -ratelimit, and -resolved
+// options, sorted by name.
+func Rules(vars map[string]string, tr lingo.Translations) ([]Rule, []error) {
+	var rs []Rule
+	var errs []error
+	for k, v := range vars {
+		if !strings.HasPrefix(k, PREFIX) || !strings.HasSuffix(k, "-rule") {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(k, PREFIX), "-rule")
+		r, err := ParseRule(name, v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf(tr.Value("error.alertrule", k, v, err.Error())))
+			continue
+		}
+		opt := PREFIX + name + "-"
+		r.Command, r.Webhook = vars[opt+"command"], vars[opt+"webhook"]
+		if v, ok := vars[opt+"ratelimit"]; ok {
+			if r.RateLimit, err = time.ParseDuration(v); err != nil {
+				errs = append(errs, fmt.Errorf(tr.Value("error.alertoption", opt+"ratelimit", v, err.Error())))
+				continue
+			}
+		}
+		if v, ok := vars[opt+"resolved"]; ok {
+			if r.Resolved, err = strconv.ParseBool(v); err != nil {
+				errs = append(errs, fmt.Errorf(tr.Value("error.alertoption", opt+"resolved", v, err.Error())))
+				continue
+			}
+		}
+		rs = append(rs, r)
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Name < rs[j].Name })
+	return rs, errs
+}
+
+// Alert is a rule whose condition holds for one of the widget's devices.
+type Alert struct {
+	Rule Rule
+	// Key is the device, e.g. CPU3 for a rule on cpu.*
+	Key   string
+	Value float64
+	// Since is when the alert fired
+	Since time.Time
+}
+
+// Metric returns the DOMAIN.KEY of the device.
+func (a Alert) Metric() string {
+	return a.Rule.Domain + "." + a.Key
+}
+
+// Event records an alert firing or resolving.
+type Event struct {
+	Alert
+	Time     time.Time
+	Resolved bool
+	// Message describes the event, as it's logged
+	Message string
+}
+
+// Engine tracks the state of the rules. It's safe for concurrent use.
+type Engine struct {
+	sync.Mutex
+	// Quiet engines track the alerts without running their actions, e.g.
+	// in a UI attached to a daemon, which runs them itself
+	Quiet bool
+	rules []Rule
+	tr    lingo.Translations
+	// When the condition started to hold, for alerts that haven't fired yet
+	pending map[string]time.Time
+	firing  map[string]*Alert
+	events  []Event
+	// When each rule last notified a firing alert
+	notified map[string]time.Time
+	// The firing alerts that were notified, whose resolution is notified
+	announced map[string]bool
+	// notify runs the actions of an event
+	notify func(Event)
+	host   string
+	client *http.Client
+}
+
+// NewEngine creates an Engine for the rules.
+func NewEngine(rules []Rule, tr lingo.Translations) *Engine {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	e := &Engine{
+		rules:     rules,
+		tr:        tr,
+		pending:   make(map[string]time.Time),
+		firing:    make(map[string]*Alert),
+		notified:  make(map[string]time.Time),
+		announced: make(map[string]bool),
+		host:      host,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+	e.notify = func(ev Event) {
+		go func() {
+			for _, err := range e.Notify(ev) {
+				log.Print(e.tr.Value("error.alertaction", ev.Rule.Name, err.Error()))
+			}
+		}()
+	}
+	return e
+}
+
+// Evaluate checks the rules against the latest values of each domain's
+// devices, and returns the alerts that fired or resolved, which are also
+// logged. An alert resolves when its condition no longer holds, or its device
+// disappears.
+func (e *Engine) Evaluate(now time.Time, samples map[string]map[string]float64) []Event {
+	e.Lock()
+	defer e.Unlock()
+	holding := make(map[string]bool)
+	values := make(map[string]float64)
+	var evs []Event
+	for _, r := range e.rules {
+		pattern := strings.ToLower(r.Key)
+		for key, v := range samples[r.Domain] {
+			if !utils.Glob(pattern, strings.ToLower(key)) {
+				continue
+			}
+			id := r.Name + "\x00" + key
+			values[id] = v
+			if !r.holds(v) {
+				continue
+			}
+			holding[id] = true
+			if a, ok := e.firing[id]; ok {
+				a.Value = v
+				continue
+			}
+			start, ok := e.pending[id]
+			if !ok {
+				start = now
+				e.pending[id] = now
+			}
+			if now.Sub(start) >= r.For {
+				delete(e.pending, id)
+				a := &Alert{Rule: r, Key: key, Value: v, Since: now}
+				e.firing[id] = a
+				evs = append(evs, Event{Alert: *a, Time: now})
+			}
+		}
+	}
+	for id := range e.pending {
+		if !holding[id] {
+			delete(e.pending, id)
+		}
+	}
+	for id, a := range e.firing {
+		if holding[id] {
+			continue
+		}
+		delete(e.firing, id)
+		ev := Event{Alert: *a, Time: now, Resolved: true}
+		if v, ok := values[id]; ok {
+			ev.Value = v
+		}
+		evs = append(evs, ev)
+	}
+	sort.SliceStable(evs, func(i, j int) bool {
+		if evs[i].Rule.Name != evs[j].Rule.Name {
+			return evs[i].Rule.Name < evs[j].Rule.Name
+		}
+		return evs[i].Key < evs[j].Key
+	})
+	for i, ev := range evs {
+		value := strconv.FormatFloat(ev.Value, 'f', 1, 64)
+		if ev.Resolved {
+			evs[i].Message = e.tr.Value("alert.resolved", ev.Rule.Name, ev.Metric(), value, ev.Rule.String())
+		} else {
+			evs[i].Message = e.tr.Value("alert.firing", ev.Rule.Name, ev.Metric(), value, ev.Rule.String())
+		}
+		log.Print(evs[i].Message)
+		e.act(evs[i])
+	}
+	e.events = append(e.events, evs...)
+	if len(e.events) > maxEvents {
+		e.events = append([]Event(nil), e.events[len(e.events)-maxEvents:]...)
+	}
+	return evs
+}
+
+// act runs the actions of the event's rule, unless they're rate limited.
+func (e *Engine) act(ev Event) {
+	r := ev.Rule
+	if e.Quiet || (r.Command == "" && r.Webhook == "") {
+		return
+	}
+	id := r.Name + "\x00" + ev.Key
+	if ev.Resolved {
+		if e.announced[id] && r.Resolved {
+			e.notify(ev)
+		}
+		delete(e.announced, id)
+		return
+	}
+	if last, ok := e.notified[r.Name]; ok && ev.Time.Sub(last) < r.RateLimit {
+		return
+	}
+	e.notified[r.Name] = ev.Time
+	e.announced[id] = true
+	e.notify(ev)
+}
+
+// Firing returns the firing alerts, sorted by rule name and device.
+func (e *Engine) Firing() []Alert {
+	e.Lock()
+	defer e.Unlock()
+	as := make([]Alert, 0, len(e.firing))
+	for _, a := range e.firing {
+		as = append(as, *a)
+	}
+	sort.Slice(as, func(i, j int) bool {
+		if as[i].Rule.Name != as[j].Rule.Name {
+			return as[i].Rule.Name < as[j].Rule.Name
+		}
+		return as[i].Key < as[j].Key
+	})
+	return as
+}
+
+// FiringDomains returns the domains that have firing alerts.
+func (e *Engine) FiringDomains() map[string]bool {
+	e.Lock()
+	defer e.Unlock()
+	ds := make(map[string]bool)
+	for _, a := range e.firing {
+		ds[a.Rule.Domain] = true
+	}
+	return ds
+}
+
+// Events returns the most recent events, oldest first.
+func (e *Engine) Events() []Event {
+	e.Lock()
+	defer e.Unlock()
+	return append([]Event(nil), e.events...)
+}
@@ -0,0 +1,112 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xxxserxxx/lingo/v2"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Rule
+		err  bool
+	}{
+		{in: "cpu.avg > 90 for 30s", want: Rule{Domain: "cpu", Key: "avg", Op: ">", Threshold: 90, For: 30 * time.Second}},
+		{in: "temp.* > 85", want: Rule{Domain: "temp", Key: "*", Op: ">", Threshold: 85}},
+		{in: "Mem.Main >= 95.5", want: Rule{Domain: "mem", Key: "Main", Op: ">=", Threshold: 95.5}},
+		{in: "disk./ used > 90", want: Rule{Domain: "disk", Key: "/ used", Op: ">", Threshold: 90}},
+		{in: "batt.* < 10 FOR 1m", want: Rule{Domain: "batt", Key: "*", Op: "<", Threshold: 10, For: time.Minute}},
+		{in: "cpu.avg > ninety", err: true},
+		{in: "cpu.avg => 90", err: true},
+		{in: "cpu > 90", err: true},
+		{in: "cpu.avg > 90 for ever", err: true},
+		{in: "cpu.avg 90", err: true},
+	}
+	for _, tc := range tests {
+		r, err := ParseRule("test", tc.in)
+		if tc.err {
+			assert.Error(t, err, tc.in)
+			continue
+		}
+		if !assert.NoError(t, err, tc.in) {
+			continue
+		}
+		tc.want.Name, tc.want.text = "test", tc.in
+		tc.want.RateLimit, tc.want.Resolved = time.Minute, true
+		assert.Equal(t, tc.want, r, tc.in)
+	}
+}
+
+func TestRules(t *testing.T) {
+	rs, errs := Rules(map[string]string{
+		"alert-hot-rule":       "temp.* > 85",
+		"alert-busy-cpu-rule":  "cpu.avg > 90",
+		"alert-hot-command":    "notify-send hot",
+		"alert-hot-ratelimit":  "5m",
+		"alert-hot-resolved":   "false",
+		"alert-bad-rule":       "cpu.avg >",
+		"alert-slow-rule":      "cpu.avg > 50",
+		"alert-slow-ratelimit": "often",
+		"remote-x-url":         "http://x",
+	}, lingo.Translations{})
+	assert.Len(t, errs, 2)
+	if assert.Len(t, rs, 2) {
+		assert.Equal(t, "busy-cpu", rs[0].Name)
+		assert.Equal(t, time.Minute, rs[0].RateLimit)
+		assert.True(t, rs[0].Resolved)
+		assert.Equal(t, "hot", rs[1].Name)
+		assert.Equal(t, "notify-send hot", rs[1].Command)
+		assert.Equal(t, 5*time.Minute, rs[1].RateLimit)
+		assert.False(t, rs[1].Resolved)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	busy, _ := ParseRule("busy", "cpu.avg > 90 for 3s")
+	hot, _ := ParseRule("hot", "temp.* > 85")
+	e := NewEngine([]Rule{busy, hot}, lingo.Translations{})
+	start := time.Unix(1600000000, 0)
+	sample := func(avg float64, temps map[string]float64) map[string]map[string]float64 {
+		return map[string]map[string]float64{
+			"cpu":  {"avg": avg, "CPU0": 100},
+			"temp": temps,
+		}
+	}
+	type event struct {
+		metric   string
+		resolved bool
+	}
+	tests := []struct {
+		samples map[string]map[string]float64
+		events  []event
+		firing  []string
+	}{
+		{sample(95, map[string]float64{"acpi": 50}), nil, nil},
+		{sample(95, map[string]float64{"acpi": 90, "nvme": 86}), []event{{"temp.acpi", false}, {"temp.nvme", false}}, []string{"temp.acpi", "temp.nvme"}},
+		// The CPU has been busy for 2s
+		{sample(80, map[string]float64{"acpi": 90, "nvme": 80}), []event{{"temp.nvme", true}}, []string{"temp.acpi"}},
+		{sample(95, map[string]float64{"acpi": 90}), nil, []string{"temp.acpi"}},
+		{sample(95, map[string]float64{"acpi": 90}), nil, []string{"temp.acpi"}},
+		{sample(95, map[string]float64{"acpi": 90}), nil, []string{"temp.acpi"}},
+		// 3s after it was busy again
+		{sample(95, map[string]float64{}), []event{{"cpu.avg", false}, {"temp.acpi", true}}, []string{"cpu.avg"}},
+	}
+	for i, tc := range tests {
+		evs := e.Evaluate(start.Add(time.Duration(i)*time.Second), tc.samples)
+		var got []event
+		for _, ev := range evs {
+			got = append(got, event{ev.Metric(), ev.Resolved})
+		}
+		assert.Equal(t, tc.events, got, "step %d", i)
+		var firing []string
+		for _, a := range e.Firing() {
+			firing = append(firing, a.Metric())
+		}
+		assert.Equal(t, tc.firing, firing, "step %d", i)
+	}
+	assert.Len(t, e.Events(), 5)
+	assert.Equal(t, map[string]bool{"cpu": true}, e.FiringDomains())
+}
@@ -9,7 +9,6 @@ import (
 	"io/fs"
 	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -20,7 +19,6 @@ import (
 
 	//_ "net/http/pprof"
 
-	"github.com/VictoriaMetrics/metrics"
 	jj "github.com/cloudfoundry-attic/jibber_jabber"
 	"github.com/droundy/goopt"
 	ui "github.com/gizak/termui/v3"
@@ -28,10 +26,14 @@ import (
 	"github.com/xxxserxxx/lingo/v2"
 
 	"github.com/xxxserxxx/gotop/v4"
+	"github.com/xxxserxxx/gotop/v4/alerts"
 	"github.com/xxxserxxx/gotop/v4/colorschemes"
+	"github.com/xxxserxxx/gotop/v4/daemon"
 	"github.com/xxxserxxx/gotop/v4/devices"
+	"github.com/xxxserxxx/gotop/v4/export"
 	"github.com/xxxserxxx/gotop/v4/layout"
 	"github.com/xxxserxxx/gotop/v4/logging"
+	"github.com/xxxserxxx/gotop/v4/utils"
 	w "github.com/xxxserxxx/gotop/v4/widgets"
 )
 
@@ -42,6 +44,10 @@ const (
 	batteryUI                 = "cpu/2 batt/1\ndisk/1 2:mem/2\ntemp\nnet procs"
 	procsUI                   = "cpu 4:procs\ndisk\nmem\nnet"
 	kitchensink               = "3:cpu/2 3:mem/1\n4:temp/1 3:disk/2\npower\n3:net 3:procs"
+
+	// flashDuration is how long the border of a widget that just became
+	// critical is inverted
+	flashDuration = 500 * time.Millisecond
 )
 
 var (
@@ -51,6 +57,9 @@ var (
 	BuildDate    = "Hadean"
 	conf         gotop.Config
 	help         *w.HelpMenu
+	hostMenu     *w.HostMenu
+	alertLog     *w.AlertLog
+	detailView   *w.DetailView
 	bar          *w.StatusBar
 	stderrLogger = log.New(os.Stderr, "", 0)
 	tr           lingo.Translations
@@ -81,12 +90,17 @@ func parseArgs() error {
 	layout := goopt.String([]string{"--layout", "-l"}, conf.Layout, tr.Value("args.layout"))
 	netinterface := goopt.String([]string{"--interface", "-i"}, "all", tr.Value("args.net"))
 	exportport := goopt.String([]string{"--export", "-x"}, conf.ExportPort, tr.Value("args.export"))
-	mbps := goopt.Flag([]string{"--mbps"}, []string{"--bytes"}, tr.Value("args.mbps"), tr.Value("args.no-mbps"))
+	bits := goopt.Flag([]string{"--bits", "--mbps"}, []string{"--bytes"}, tr.Value("args.mbps"), tr.Value("args.bytes"))
+	units := goopt.String([]string{"--units"}, conf.Units.Prefixes(), tr.Value("args.units"))
+	bell := goopt.Flag([]string{"--bell"}, []string{}, tr.Value("args.bell"), "")
 	test := goopt.Flag([]string{"--test"}, []string{"--no-test"}, tr.Value("args.test"), tr.Value("args.no-test"))
 	// This is so the flag package doesn't barf on an unrecognized flag; it's processed earlier
 	goopt.String([]string{"-C"}, "", tr.Value("args.conffile"))
 	nvidia := goopt.Flag([]string{"--nvidia"}, []string{"--no-nvidia"}, tr.Value("args.nvidia"), tr.Value("args.no-nvidia"))
 	list := goopt.String([]string{"--list"}, "", tr.Value("args.list"))
+	daemonMode := goopt.Flag([]string{"--daemon"}, []string{}, tr.Value("args.daemon"), "")
+	attach := goopt.Flag([]string{"--attach"}, []string{}, tr.Value("args.attach"), "")
+	socket := goopt.String([]string{"--socket"}, conf.Socket, tr.Value("args.socket"))
 	wc := goopt.Flag([]string{"--write-config"}, []string{}, tr.Value("args.write"), "")
 	goopt.Parse(nil)
 
@@ -98,19 +112,30 @@ func parseArgs() error {
 	conf.Layout = *layout
 	conf.NetInterface = *netinterface
 	conf.ExportPort = *exportport
-	conf.Mbps = *mbps
 	conf.Nvidia = *nvidia
 	conf.AverageLoad = *averageload
 	conf.Test = *test
 	conf.Statusbar = *statusbar
-	conf.Mbps = *mbps
+	if *bits {
+		conf.Units.Bits = true
+	}
 	conf.Nvidia = *nvidia
+	conf.Daemon = *daemonMode
+	conf.Attach = *attach
+	conf.Socket = *socket
+	if *bell {
+		conf.Bell = true
+	}
 	if upInt, err := time.ParseDuration(*updateinterval); err == nil {
 		conf.UpdateInterval = upInt
 	} else {
 		fmt.Printf("Update interval must be a time interval such as '10s' or '1m'")
 		os.Exit(1)
 	}
+	if err := conf.Units.SetPrefixes(*units); err != nil {
+		return fmt.Errorf(tr.Value("config.err.units", *units))
+	}
+	utils.SetUnits(conf.Units)
 	if *tempScale {
 		conf.TempScale = 'F'
 	} else {
@@ -128,6 +153,7 @@ func parseArgs() error {
 		}
 		conf.Colorscheme = cs
 	}
+	devices.SetDiskFilter(conf.Disks)
 	if *list != "" {
 		switch *list {
 		case "layouts":
@@ -192,9 +218,34 @@ func setDefaultTermuiColors(c gotop.Config) {
 	ui.Theme.Default = ui.NewStyle(ui.Color(c.Colorscheme.Fg), ui.Color(c.Colorscheme.Bg))
 	ui.Theme.Block.Title = ui.NewStyle(ui.Color(c.Colorscheme.BorderLabel), ui.Color(c.Colorscheme.Bg))
 	ui.Theme.Block.Border = ui.NewStyle(ui.Color(c.Colorscheme.BorderLine), ui.Color(c.Colorscheme.Bg))
+	w.AlertColor = ui.Color(c.Colorscheme.TempHigh)
+	w.FocusColor = ui.Color(c.Colorscheme.ProcCursor)
+}
+
+// hostGrids holds the UI for each host that has been viewed; grids for remotes
+// are only created when they're first selected.
+type hostGrids struct {
+	grids   map[string]*layout.MyGrid
+	newGrid func(host string) (*layout.MyGrid, error)
+}
+
+// get returns the grid for host, creating it if necessary.
+func (hg hostGrids) get(c gotop.Config, host string) (*layout.MyGrid, error) {
+	if g, ok := hg.grids[host]; ok {
+		return g, nil
+	}
+	g, err := hg.newGrid(host)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range g.Lines {
+		item.Scale(c.GraphHorizontalScale)
+	}
+	hg.grids[host] = g
+	return g, nil
 }
 
-func eventLoop(c gotop.Config, grid *layout.MyGrid) {
+func eventLoop(c gotop.Config, grids hostGrids) {
 	drawTicker := time.NewTicker(c.UpdateInterval).C
 
 	// handles kill signal sent to gotop
@@ -203,22 +254,195 @@ func eventLoop(c gotop.Config, grid *layout.MyGrid) {
 
 	uiEvents := ui.PollEvents()
 
-	previousKey := ""
+	grid := grids.grids[devices.Local]
+	termWidth, termHeight := ui.TerminalDimensions()
+	hostMenuVisible := false
+	alertLogVisible := false
+	detailVisible := false
+	// Widgets whose border is inverted because they just became critical
+	var flashed []w.Critical
+	var unflash <-chan time.Time
+	setPage := func(i int) {
+		if i == grid.Page() || !grid.SetPage(i) {
+			return
+		}
+		bar.Page = grid.Page()
+		ui.Clear()
+		ui.Render(grid)
+		if c.Statusbar {
+			ui.Render(bar)
+		}
+	}
 
 	for {
 		select {
 		case <-sigTerm:
 			return
+		case <-unflash:
+			for _, f := range flashed {
+				f.Lock()
+				f.Invert(false)
+				f.Unlock()
+			}
+			flashed, unflash = nil, nil
+			if !c.HelpVisible && !hostMenuVisible && !alertLogVisible && !detailVisible {
+				ui.Render(grid)
+			}
 		case <-drawTicker:
-			if !c.HelpVisible {
+			if c.Bell && flashed == nil {
+				if flashed = flashCritical(grid); flashed != nil {
+					unflash = time.After(flashDuration)
+				}
+			}
+			if alertLogVisible {
+				alertLog.Update()
+				ui.Render(alertLog)
+			} else if detailVisible {
+				// The item goes away if, e.g., a disk is unmounted
+				if detailVisible = detailView.Update(); detailVisible {
+					ui.Render(detailView)
+				} else {
+					ui.Clear()
+					ui.Render(grid)
+				}
+			} else if !c.HelpVisible && !hostMenuVisible {
 				ui.Render(grid)
 				if c.Statusbar {
 					ui.Render(bar)
 				}
 			}
 		case e := <-uiEvents:
-			if grid.Proc != nil && grid.Proc.HandleEvent(e) {
-				ui.Render(grid.Proc)
+			if detailVisible {
+				switch e.ID {
+				case "q", "<C-c>":
+					return
+				case "k", "<Up>", "<MouseWheelUp>":
+					detailView.ScrollUp()
+				case "j", "<Down>", "<MouseWheelDown>":
+					detailView.ScrollDown()
+				case "<Enter>", "<Escape>":
+					detailVisible = false
+				case "<Resize>":
+					payload := e.Payload.(ui.Resize)
+					termWidth, termHeight = payload.Width, payload.Height
+					detailView.Resize(termWidth, termHeight)
+					alertLog.Resize(termWidth, termHeight)
+					hostMenu.Resize(termWidth, termHeight)
+					help.Resize(termWidth, termHeight)
+					if c.Statusbar {
+						grid.SetRect(0, 0, termWidth, termHeight-1)
+						bar.SetRect(0, termHeight-1, termWidth, termHeight)
+					} else {
+						grid.SetRect(0, 0, termWidth, termHeight)
+					}
+				}
+				ui.Clear()
+				if detailVisible {
+					ui.Render(detailView)
+				} else {
+					ui.Render(grid)
+					if c.Statusbar {
+						ui.Render(bar)
+					}
+				}
+				break
+			}
+			if alertLogVisible {
+				switch e.ID {
+				case "q", "<C-c>":
+					return
+				case "k", "<Up>", "<MouseWheelUp>":
+					alertLog.ScrollUp()
+				case "j", "<Down>", "<MouseWheelDown>":
+					alertLog.ScrollDown()
+				case "<C-u>", "<PageUp>":
+					alertLog.ScrollPageUp()
+				case "<C-d>", "<PageDown>":
+					alertLog.ScrollPageDown()
+				case "g", "<Home>":
+					alertLog.ScrollTop()
+				case "G", "<End>":
+					alertLog.ScrollBottom()
+				case "a", "<Escape>":
+					alertLogVisible = false
+				case "<Resize>":
+					payload := e.Payload.(ui.Resize)
+					termWidth, termHeight = payload.Width, payload.Height
+					alertLog.Resize(termWidth, termHeight)
+					hostMenu.Resize(termWidth, termHeight)
+					help.Resize(termWidth, termHeight)
+					detailView.Resize(termWidth, termHeight)
+					if c.Statusbar {
+						grid.SetRect(0, 0, termWidth, termHeight-1)
+						bar.SetRect(0, termHeight-1, termWidth, termHeight)
+					} else {
+						grid.SetRect(0, 0, termWidth, termHeight)
+					}
+				}
+				ui.Clear()
+				if alertLogVisible {
+					ui.Render(alertLog)
+				} else {
+					ui.Render(grid)
+					if c.Statusbar {
+						ui.Render(bar)
+					}
+				}
+				break
+			}
+			if hostMenuVisible {
+				switch e.ID {
+				case "q", "<C-c>":
+					return
+				case "k", "<Up>", "<MouseWheelUp>":
+					hostMenu.ScrollUp()
+				case "j", "<Down>", "<MouseWheelDown>":
+					hostMenu.ScrollDown()
+				case "<Enter>":
+					host := hostMenu.Selected()
+					g, err := grids.get(c, host)
+					if err != nil {
+						log.Print(err)
+						break
+					}
+					g.SetPage(grid.Page())
+					grid = g
+					bar.Host = host
+					if c.Statusbar {
+						grid.SetRect(0, 0, termWidth, termHeight-1)
+					} else {
+						grid.SetRect(0, 0, termWidth, termHeight)
+					}
+					hostMenuVisible = false
+				case "H", "<Escape>":
+					hostMenuVisible = false
+				case "<Resize>":
+					payload := e.Payload.(ui.Resize)
+					termWidth, termHeight = payload.Width, payload.Height
+					hostMenu.Resize(termWidth, termHeight)
+					help.Resize(termWidth, termHeight)
+					alertLog.Resize(termWidth, termHeight)
+					detailView.Resize(termWidth, termHeight)
+					if c.Statusbar {
+						grid.SetRect(0, 0, termWidth, termHeight-1)
+						bar.SetRect(0, termHeight-1, termWidth, termHeight)
+					} else {
+						grid.SetRect(0, 0, termWidth, termHeight)
+					}
+				}
+				ui.Clear()
+				if hostMenuVisible {
+					ui.Render(hostMenu)
+				} else {
+					ui.Render(grid)
+					if c.Statusbar {
+						ui.Render(bar)
+					}
+				}
+				break
+			}
+			if !c.HelpVisible && grid.HandleEvent(e) {
+				ui.Render(grid)
 				break
 			}
 			switch e.ID {
@@ -228,7 +452,7 @@ func eventLoop(c gotop.Config, grid *layout.MyGrid) {
 				c.HelpVisible = !c.HelpVisible
 			case "<Resize>":
 				payload := e.Payload.(ui.Resize)
-				termWidth, termHeight := payload.Width, payload.Height
+				termWidth, termHeight = payload.Width, payload.Height
 				if c.Statusbar {
 					grid.SetRect(0, 0, termWidth, termHeight-1)
 					bar.SetRect(0, termHeight-1, termWidth, termHeight)
@@ -236,6 +460,9 @@ func eventLoop(c gotop.Config, grid *layout.MyGrid) {
 					grid.SetRect(0, 0, payload.Width, payload.Height)
 				}
 				help.Resize(payload.Width, payload.Height)
+				hostMenu.Resize(payload.Width, payload.Height)
+				alertLog.Resize(payload.Width, payload.Height)
+				detailView.Resize(payload.Width, payload.Height)
 				ui.Clear()
 			}
 
@@ -265,111 +492,51 @@ func eventLoop(c gotop.Config, grid *layout.MyGrid) {
 						c.GraphHorizontalScale -= graphHorizontalScaleDelta
 						for _, item := range grid.Lines {
 							item.Scale(c.GraphHorizontalScale)
-							ui.Render(item)
 						}
+						ui.Render(grid)
 					}
-				case "b":
-					if grid.Net != nil {
-						grid.Net.Mbps = !grid.Net.Mbps
+				case "H":
+					hostMenu.SetHosts(devices.RemoteNames(), bar.Host)
+					hostMenu.Resize(termWidth, termHeight)
+					hostMenuVisible = true
+					ui.Clear()
+					ui.Render(hostMenu)
+				case "<Enter>":
+					if d, ok := grid.Focused().(w.Detailed); ok && detailView.Show(d) {
+						detailView.Resize(termWidth, termHeight)
+						detailVisible = true
+						ui.Clear()
+						ui.Render(detailView)
 					}
+				case "a":
+					alertLog.Update()
+					alertLog.Resize(termWidth, termHeight)
+					alertLogVisible = true
+					ui.Clear()
+					ui.Render(alertLog)
 				case "<Resize>":
 					ui.Render(grid)
 					if c.Statusbar {
 						ui.Render(bar)
 					}
-				case "<MouseLeft>":
-					if grid.Proc != nil {
-						payload := e.Payload.(ui.Mouse)
-						grid.Proc.HandleClick(payload.X, payload.Y)
-						ui.Render(grid.Proc)
-					}
-				case "k", "<Up>", "<MouseWheelUp>":
-					if grid.Proc != nil {
-						grid.Proc.ScrollUp()
-						ui.Render(grid.Proc)
-					}
-				case "j", "<Down>", "<MouseWheelDown>":
-					if grid.Proc != nil {
-						grid.Proc.ScrollDown()
-						ui.Render(grid.Proc)
-					}
-				case "<Home>":
-					if grid.Proc != nil {
-						grid.Proc.ScrollTop()
-						ui.Render(grid.Proc)
-					}
-				case "g":
-					if grid.Proc != nil {
-						if previousKey == "g" {
-							grid.Proc.ScrollTop()
-							ui.Render(grid.Proc)
-						}
-					}
-				case "G", "<End>":
-					if grid.Proc != nil {
-						grid.Proc.ScrollBottom()
-						ui.Render(grid.Proc)
-					}
-				case "<C-d>":
-					if grid.Proc != nil {
-						grid.Proc.ScrollHalfPageDown()
-						ui.Render(grid.Proc)
-					}
-				case "<C-u>":
-					if grid.Proc != nil {
-						grid.Proc.ScrollHalfPageUp()
-						ui.Render(grid.Proc)
-					}
-				case "<C-f>", "<PageDown>":
-					if grid.Proc != nil {
-						grid.Proc.ScrollPageDown()
-						ui.Render(grid.Proc)
-					}
-				case "<C-b>", "<PageUp>":
-					if grid.Proc != nil {
-						grid.Proc.ScrollPageUp()
-						ui.Render(grid.Proc)
-					}
-				case "d":
-					if grid.Proc != nil {
-						if previousKey == "d" {
-							grid.Proc.KillProc("SIGTERM")
-						}
-					}
-				case "3":
-					if grid.Proc != nil {
-						if previousKey == "d" {
-							grid.Proc.KillProc("SIGQUIT")
-						}
-					}
-				case "9":
-					if grid.Proc != nil {
-						if previousKey == "d" {
-							grid.Proc.KillProc("SIGKILL")
-						}
-					}
+				case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+					setPage(int(e.ID[0] - '1'))
+				case "[":
+					n := len(grid.Pages())
+					setPage((grid.Page() + n - 1) % n)
+				case "]":
+					setPage((grid.Page() + 1) % len(grid.Pages()))
 				case "<Tab>":
-					if grid.Proc != nil {
-						grid.Proc.ToggleShowingGroupedProcs()
-						ui.Render(grid.Proc)
-					}
-				case "m", "c", "n", "p":
-					if grid.Proc != nil {
-						grid.Proc.ChangeProcSortMethod(w.ProcSortMethod(e.ID))
-						ui.Render(grid.Proc)
-					}
-				case "/":
-					if grid.Proc != nil {
-						grid.Proc.SetEditingFilter(true)
-						ui.Render(grid.Proc)
+					grid.FocusNext()
+					ui.Render(grid)
+				case "z":
+					grid.ToggleZoom()
+					ui.Clear()
+					ui.Render(grid)
+					if c.Statusbar {
+						ui.Render(bar)
 					}
 				}
-
-				if previousKey == e.ID {
-					previousKey = ""
-				} else {
-					previousKey = e.ID
-				}
 			}
 
 		}
@@ -444,22 +611,66 @@ func run() int {
 	}
 	defer logfile.Close()
 
-	// device initialization errors do not stop execution
-	for _, err := range devices.Startup(conf.ExtensionVars) {
-		stderrLogger.Print(err)
+	if conf.Daemon && conf.Attach {
+		stderrLogger.Print(tr.Value("error.daemonattach"))
+		return 2
 	}
 
-	lstream, err := getLayout(conf)
-	if err != nil {
+	rules, errs := alerts.Rules(conf.ExtensionVars, tr)
+	for _, err := range errs {
 		stderrLogger.Print(err)
-		return 1
 	}
-	ly := layout.ParseLayout(lstream)
+	engine := alerts.NewEngine(rules, tr)
+
+	var spec string
+	var client *daemon.Client
+	var snap daemon.Snapshot
+	if conf.Attach {
+		// The daemon does all of the collecting and exporting; this UI only
+		// displays the daemon's layout.
+		client, snap, err = attachDaemon(conf)
+		if err != nil {
+			stderrLogger.Print(tr.Value("error.attach", conf.Socket, err.Error()))
+			return 1
+		}
+		defer client.Close()
+		spec = snap.Layout
+		conf.UpdateInterval = snap.Interval
+		// The daemon runs the alert actions; attached UIs only show alerts
+		engine.Quiet = true
+		w.SetPassive(true)
+	} else {
+		// device initialization errors do not stop execution
+		for _, err := range devices.Startup(conf.ExtensionVars) {
+			stderrLogger.Print(err)
+		}
+
+		lstream, err := getLayout(conf)
+		if err != nil {
+			stderrLogger.Print(err)
+			return 1
+		}
+		bs, err := io.ReadAll(lstream)
+		if err != nil {
+			stderrLogger.Print(err)
+			return 1
+		}
+		spec = string(bs)
+	}
+	ly := layout.ParseLayout(strings.NewReader(spec))
 
 	if conf.Test {
 		return runTests(conf)
 	}
 
+	if !conf.Attach {
+		startExports(conf)
+	}
+
+	if conf.Daemon {
+		return runDaemon(conf, spec, engine)
+	}
+
 	if err = ui.Init(); err != nil {
 		stderrLogger.Print(err)
 		return 1
@@ -468,15 +679,19 @@ func run() int {
 
 	setDefaultTermuiColors(conf) // done before initializing widgets to allow inheriting colors
 	help = w.NewHelpMenu(tr)
-	if conf.Statusbar {
-		bar = w.NewStatusBar()
-	}
-
-	grid, err := layout.Layout(ly, conf)
+	hostMenu = w.NewHostMenu()
+	// The status bar also tracks the host being viewed, even when it's hidden
+	bar = w.NewStatusBar()
+	bar.Alerts = engine
+	alertLog = w.NewAlertLog(engine)
+	detailView = w.NewDetailView()
+
+	grid, err := layout.Layout(ly, conf, devices.Local)
 	if err != nil {
 		stderrLogger.Print(err)
 		return 1
 	}
+	bar.Pages = grid.Pages()
 
 	termWidth, termHeight := ui.TerminalDimensions()
 	if conf.Statusbar {
@@ -492,20 +707,160 @@ func run() int {
 		ui.Render(bar)
 	}
 
+	if client != nil {
+		daemon.Apply(grid.Stateful, snap)
+		go followDaemon(conf, client, grid, 4*termWidth)
+	}
+	if len(rules) > 0 {
+		go watchAlerts(conf, engine, grid)
+	}
+
+	grids := hostGrids{
+		grids: map[string]*layout.MyGrid{devices.Local: grid},
+		newGrid: func(host string) (*layout.MyGrid, error) {
+			return layout.Layout(ly, conf, host)
+		},
+	}
+	eventLoop(conf, grids)
+	return 0
+}
+
+// startExports starts every configured form of metrics export.
+func startExports(c gotop.Config) {
 	// TODO https://godoc.org/github.com/VictoriaMetrics/metrics#Set
-	if conf.ExportPort != "" {
+	if c.ExportPort != "" {
 		go func() {
-			http.HandleFunc("/metrics", func(w http.ResponseWriter, req *http.Request) {
-				metrics.WritePrometheus(w, true)
-			})
-			http.ListenAndServe(conf.ExportPort, nil)
+			if err := export.Serve(c); err != nil {
+				log.Print(tr.Value("error.export", err.Error()))
+			}
 		}()
 	}
+	if c.PushURL != "" {
+		if p, err := export.NewPusher(c); err == nil {
+			go p.Run()
+		} else {
+			stderrLogger.Print(err)
+		}
+	}
+	for _, err := range export.Start(c) {
+		stderrLogger.Print(err)
+	}
+}
+
+// flashCritical rings the terminal bell, and inverts the borders of the
+// grid's widgets that have just become critical, which it returns.
+func flashCritical(grid *layout.MyGrid) []w.Critical {
+	var crossed []w.Critical
+	for _, cr := range grid.Critical {
+		cr.Lock()
+		if cr.Critical() {
+			cr.Invert(true)
+			crossed = append(crossed, cr)
+		}
+		cr.Unlock()
+	}
+	if crossed != nil {
+		fmt.Print("\a")
+	}
+	return crossed
+}
+
+// watchAlerts evaluates the alert rules against the grid's widgets every
+// update interval, and highlights the widgets that have firing alerts.
+func watchAlerts(c gotop.Config, engine *alerts.Engine, grid *layout.MyGrid) {
+	domains := make([]string, len(grid.Samplers))
+	for range time.NewTicker(c.UpdateInterval).C {
+		samples := make(map[string]map[string]float64)
+		for i, s := range grid.Samplers {
+			s.Lock()
+			domain, vs := s.Samples()
+			s.Unlock()
+			domains[i] = domain
+			if samples[domain] == nil {
+				samples[domain] = vs
+				continue
+			}
+			for k, v := range vs {
+				samples[domain][k] = v
+			}
+		}
+		engine.Evaluate(time.Now(), samples)
+		firing := engine.FiringDomains()
+		for i, s := range grid.Samplers {
+			s.Lock()
+			s.SetAlert(firing[domains[i]])
+			s.Unlock()
+		}
+	}
+}
+
+// runDaemon collects data for the layout, without displaying it, and serves
+// it to attached UIs until gotop is killed.
+func runDaemon(c gotop.Config, spec string, engine *alerts.Engine) int {
+	grid, err := layout.Layout(layout.ParseLayout(strings.NewReader(spec)), c, devices.Local)
+	if err != nil {
+		stderrLogger.Print(err)
+		return 1
+	}
+	go watchAlerts(c, engine, grid)
+	srv, err := daemon.Listen(c.Socket, spec, c.UpdateInterval, grid.Stateful, c.DaemonHistory)
+	if err != nil {
+		stderrLogger.Print(tr.Value("error.daemon", c.Socket, err.Error()))
+		return 1
+	}
+	defer srv.Close()
+	go func() {
+		if err := srv.Serve(); err != nil {
+			log.Print(tr.Value("error.daemon", c.Socket, err.Error()))
+		}
+	}()
 
-	eventLoop(conf, grid)
+	sigTerm := make(chan os.Signal, 2)
+	signal.Notify(sigTerm, os.Interrupt, syscall.SIGTERM)
+	<-sigTerm
 	return 0
 }
 
+// attachDaemon connects to the daemon and fetches its layout and initial state.
+func attachDaemon(c gotop.Config) (*daemon.Client, daemon.Snapshot, error) {
+	client, err := daemon.Dial(c.Socket)
+	if err != nil {
+		return nil, daemon.Snapshot{}, err
+	}
+	snap, err := client.Fetch(0)
+	if err != nil {
+		client.Close()
+		return nil, snap, err
+	}
+	return client, snap, nil
+}
+
+// followDaemon copies the daemon's state into the grid every update
+// interval, reconnecting if the daemon goes away.
+func followDaemon(c gotop.Config, client *daemon.Client, grid *layout.MyGrid, points int) {
+	var lastErr error
+	for range time.NewTicker(c.UpdateInterval).C {
+		if client == nil {
+			var err error
+			if client, err = daemon.Dial(c.Socket); err != nil {
+				continue
+			}
+		}
+		snap, err := client.Fetch(points)
+		if err != nil {
+			if lastErr == nil {
+				log.Print(tr.Value("error.attach", c.Socket, err.Error()))
+			}
+			lastErr = err
+			client.Close()
+			client = nil
+			continue
+		}
+		lastErr = nil
+		daemon.Apply(grid.Stateful, snap)
+	}
+}
+
 func getLayout(conf gotop.Config) (io.Reader, error) {
 	switch conf.Layout {
 	case "-":
@@ -551,4 +906,16 @@ func listDevices() {
 			fmt.Printf("\t%s\n", d)
 		}
 	}
+	included, excluded, err := devices.Partitions()
+	if err != nil {
+		fmt.Println(tr.Value("error.fatalfetch", "disk", err.Error()))
+		return
+	}
+	fmt.Println(tr.Value("help.disks"))
+	for _, p := range included {
+		fmt.Printf("\t%s on %s (%s)\n", p.Device, p.Mountpoint, p.Fstype)
+	}
+	for _, p := range excluded {
+		fmt.Printf("\t!%s on %s (%s)\n", p.Device, p.Mountpoint, p.Fstype)
+	}
 }
@@ -10,12 +10,15 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/shibukawa/configdir"
 	"github.com/xxxserxxx/gotop/v4/colorschemes"
+	"github.com/xxxserxxx/gotop/v4/devices"
+	"github.com/xxxserxxx/gotop/v4/utils"
 	"github.com/xxxserxxx/gotop/v4/widgets"
 	"github.com/xxxserxxx/lingo/v2"
 )
@@ -27,6 +30,9 @@ var Dicts embed.FS
 // CONFFILE is the name of the default config file
 const CONFFILE = "gotop.conf"
 
+// SOCKFILE is the name of the default daemon socket, in the cache directory
+const SOCKFILE = "gotop.sock"
+
 type Config struct {
 	ConfigDir            configdir.ConfigDir
 	GraphHorizontalScale int
@@ -41,9 +47,38 @@ type Config struct {
 	Layout               string
 	MaxLogSize           int64
 	ExportPort           string
-	Mbps                 bool
+	ExportTLSCert        string
+	ExportTLSKey         string
+	ExportUser           string
+	ExportPassword       string
+	ExportToken          string
+	PushURL              string
+	PushFormat           string
+	PushJob              string
+	PushInstance         string
+	PushInterval         time.Duration
+	InfluxURL            string
+	InfluxToken          string
+	StatsdAddr           string
+	StatsdPrefix         string
+	SampleLog            string
+	SampleLogSplit       bool
+	SampleLogSize        int64
+	Socket               string
+	DaemonHistory        time.Duration
+	Units                utils.Units
+	NetPackets           bool
+	SparklineLog         bool
+	SparklineShared      bool
+	SparklineLegend      bool
+	Bell                 bool
 	Temps                []string
+	Disks                devices.DiskFilter
+	DiskColumns          []string
+	DiskWarn             int
 	Test                 bool
+	Daemon               bool
+	Attach               bool
 	ExtensionVars        map[string]string
 	ConfigFile           string
 	Tr                   lingo.Translations
@@ -67,9 +102,16 @@ func NewConfig() Config {
 		NetInterface:         widgets.NetInterfaceAll,
 		MaxLogSize:           5000000,
 		Layout:               "default",
+		DaemonHistory:        time.Hour,
+		PushInterval:         15 * time.Second,
+		StatsdPrefix:         "gotop",
+		SampleLogSize:        5000000,
+		Disks:                devices.DefaultDiskFilter,
+		DiskWarn:             90,
 		ExtensionVars:        make(map[string]string),
 	}
 	conf.Colorscheme, _ = colorschemes.FromName(conf.ConfigDir, "default")
+	conf.Socket = filepath.Join(cd.QueryCacheFolder().Path, SOCKFILE)
 	folder := conf.ConfigDir.QueryFolderContainsFile(CONFFILE)
 	if folder != nil {
 		conf.ConfigFile = filepath.Join(folder.Path, CONFFILE)
@@ -105,7 +147,7 @@ func load(in io.Reader, conf *Config) error {
 		if l[0] == '#' {
 			continue
 		}
-		kv := strings.Split(l, "=")
+		kv := strings.SplitN(l, "=", 2)
 		if len(kv) != 2 {
 			return fmt.Errorf(conf.Tr.Value("config.err.configsyntax", l))
 		}
@@ -180,10 +222,122 @@ func load(in io.Reader, conf *Config) error {
 			conf.MaxLogSize = int64(iv)
 		case export:
 			conf.ExportPort = kv[1]
-		case mbps:
-			conf.Mbps = true
+		case exporttlscert:
+			conf.ExportTLSCert = kv[1]
+		case exporttlskey:
+			conf.ExportTLSKey = kv[1]
+		case exportuser:
+			conf.ExportUser = kv[1]
+		case exportpassword:
+			conf.ExportPassword = kv[1]
+		case exporttoken:
+			conf.ExportToken = kv[1]
+		case pushurl:
+			conf.PushURL = kv[1]
+		case pushformat:
+			conf.PushFormat = kv[1]
+		case pushjob:
+			conf.PushJob = kv[1]
+		case pushinstance:
+			conf.PushInstance = kv[1]
+		case pushinterval:
+			d, err := time.ParseDuration(kv[1])
+			if err != nil {
+				return fmt.Errorf(conf.Tr.Value("config.err.line", ln, err.Error()))
+			}
+			conf.PushInterval = d
+		case influxurl:
+			conf.InfluxURL = kv[1]
+		case influxtoken:
+			conf.InfluxToken = kv[1]
+		case statsdaddr:
+			conf.StatsdAddr = kv[1]
+		case statsdprefix:
+			conf.StatsdPrefix = kv[1]
+		case samplelog:
+			conf.SampleLog = kv[1]
+		case samplelogsplit:
+			bv, err := strconv.ParseBool(kv[1])
+			if err != nil {
+				return fmt.Errorf(conf.Tr.Value("config.err.line", ln, err.Error()))
+			}
+			conf.SampleLogSplit = bv
+		case samplelogsize:
+			iv, err := strconv.Atoi(kv[1])
+			if err != nil {
+				return fmt.Errorf(conf.Tr.Value("config.err.line", ln, err.Error()))
+			}
+			conf.SampleLogSize = int64(iv)
+		case socket:
+			conf.Socket = kv[1]
+		case daemonhistory:
+			d, err := time.ParseDuration(kv[1])
+			if err != nil {
+				return fmt.Errorf(conf.Tr.Value("config.err.line", ln, err.Error()))
+			}
+			conf.DaemonHistory = d
+		case bell:
+			bv, err := strconv.ParseBool(kv[1])
+			if err != nil {
+				return fmt.Errorf(conf.Tr.Value("config.err.line", ln, err.Error()))
+			}
+			conf.Bell = bv
+		case units:
+			if err := conf.Units.SetPrefixes(kv[1]); err != nil {
+				return fmt.Errorf(conf.Tr.Value("config.err.units", kv[1]))
+			}
+		case bits, mbps:
+			bv, err := strconv.ParseBool(kv[1])
+			if err != nil {
+				return fmt.Errorf(conf.Tr.Value("config.err.line", ln, err.Error()))
+			}
+			conf.Units.Bits = bv
+		case netpackets:
+			bv, err := strconv.ParseBool(kv[1])
+			if err != nil {
+				return fmt.Errorf(conf.Tr.Value("config.err.line", ln, err.Error()))
+			}
+			conf.NetPackets = bv
+		case sparklinelog, sparklineshared, sparklinelegend:
+			bv, err := strconv.ParseBool(kv[1])
+			if err != nil {
+				return fmt.Errorf(conf.Tr.Value("config.err.line", ln, err.Error()))
+			}
+			switch key {
+			case sparklinelog:
+				conf.SparklineLog = bv
+			case sparklineshared:
+				conf.SparklineShared = bv
+			default:
+				conf.SparklineLegend = bv
+			}
 		case temperatures:
 			conf.Temps = strings.Split(kv[1], ",")
+		case diskdevices:
+			conf.Disks.Devices = splitList(kv[1])
+		case diskmounts:
+			conf.Disks.Mounts = splitList(kv[1])
+		case diskfstypes:
+			conf.Disks.Fstypes = splitList(kv[1])
+		case diskpseudo:
+			bv, err := strconv.ParseBool(kv[1])
+			if err != nil {
+				return fmt.Errorf(conf.Tr.Value("config.err.line", ln, err.Error()))
+			}
+			conf.Disks.Pseudo = bv
+		case diskcolumns:
+			conf.DiskColumns = splitList(kv[1])
+			for _, c := range conf.DiskColumns {
+				if _, ok := widgets.DiskColumns[c]; !ok {
+					return fmt.Errorf(conf.Tr.Value("config.err.diskcolumn", c))
+				}
+			}
+		case diskwarn:
+			iv, err := strconv.Atoi(kv[1])
+			if err != nil {
+				return fmt.Errorf(conf.Tr.Value("config.err.line", ln, err.Error()))
+			}
+			conf.DiskWarn = iv
 		case nvidia:
 			nv, err := strconv.ParseBool(kv[1])
 			if err != nil {
@@ -262,20 +416,146 @@ func marshal(c *Config) []byte {
 		fmt.Fprint(buff, "#")
 	}
 	fmt.Fprintf(buff, "%s=%s\n", export, c.ExportPort)
-	fmt.Fprintln(buff, "# Display network IO in mpbs if true")
-	fmt.Fprintf(buff, "%s=%t\n", mbps, c.Mbps)
+	fmt.Fprintln(buff, "# If both are set, serve metrics over HTTPS using this PEM certificate and key")
+	commentIfEmpty(buff, c.ExportTLSCert)
+	fmt.Fprintf(buff, "%s=%s\n", exporttlscert, c.ExportTLSCert)
+	commentIfEmpty(buff, c.ExportTLSKey)
+	fmt.Fprintf(buff, "%s=%s\n", exporttlskey, c.ExportTLSKey)
+	fmt.Fprintln(buff, "# Require HTTP basic authentication and/or a bearer token to read metrics")
+	commentIfEmpty(buff, c.ExportUser)
+	fmt.Fprintf(buff, "%s=%s\n", exportuser, c.ExportUser)
+	commentIfEmpty(buff, c.ExportPassword)
+	fmt.Fprintf(buff, "%s=%s\n", exportpassword, c.ExportPassword)
+	commentIfEmpty(buff, c.ExportToken)
+	fmt.Fprintf(buff, "%s=%s\n", exporttoken, c.ExportToken)
+	fmt.Fprintln(buff, "# If set, push metrics to this Pushgateway or Prometheus remote-write URL")
+	commentIfEmpty(buff, c.PushURL)
+	fmt.Fprintf(buff, "%s=%s\n", pushurl, c.PushURL)
+	fmt.Fprintln(buff, "# pushgateway (the default) or remotewrite")
+	commentIfEmpty(buff, c.PushFormat)
+	fmt.Fprintf(buff, "%s=%s\n", pushformat, c.PushFormat)
+	fmt.Fprintln(buff, "# The job and instance labels of pushed metrics; by default gotop and the host name")
+	commentIfEmpty(buff, c.PushJob)
+	fmt.Fprintf(buff, "%s=%s\n", pushjob, c.PushJob)
+	commentIfEmpty(buff, c.PushInstance)
+	fmt.Fprintf(buff, "%s=%s\n", pushinstance, c.PushInstance)
+	fmt.Fprintln(buff, "# How often to push metrics, as a duration")
+	fmt.Fprintf(buff, "%s=%s\n", pushinterval, c.PushInterval)
+	fmt.Fprintln(buff, "# If set, write metrics in the InfluxDB line protocol to this udp://HOST:PORT,\n# http(s) write URL, or file")
+	commentIfEmpty(buff, c.InfluxURL)
+	fmt.Fprintf(buff, "%s=%s\n", influxurl, c.InfluxURL)
+	fmt.Fprintln(buff, "# An InfluxDB API token for HTTP writes")
+	commentIfEmpty(buff, c.InfluxToken)
+	fmt.Fprintf(buff, "%s=%s\n", influxtoken, c.InfluxToken)
+	fmt.Fprintln(buff, "# If set, send metrics as StatsD gauges to this HOST:PORT over UDP")
+	commentIfEmpty(buff, c.StatsdAddr)
+	fmt.Fprintf(buff, "%s=%s\n", statsdaddr, c.StatsdAddr)
+	fmt.Fprintln(buff, "# The prefix of StatsD gauge names")
+	fmt.Fprintf(buff, "%s=%s\n", statsdprefix, c.StatsdPrefix)
+	fmt.Fprintln(buff, "# If set to csv or ndjson, log every sample to files in the cache directory")
+	commentIfEmpty(buff, c.SampleLog)
+	fmt.Fprintf(buff, "%s=%s\n", samplelog, c.SampleLog)
+	fmt.Fprintln(buff, "# If true, log samples to one file per domain (cpu, memory, ...) instead of one file")
+	fmt.Fprintf(buff, "%s=%t\n", samplelogsplit, c.SampleLogSplit)
+	fmt.Fprintln(buff, "# The maximum sample log file size, in bytes")
+	fmt.Fprintf(buff, "%s=%d\n", samplelogsize, c.SampleLogSize)
+	fmt.Fprintln(buff, "# The Unix socket used by `--daemon` and `--attach`")
+	fmt.Fprintf(buff, "%s=%s\n", socket, c.Socket)
+	fmt.Fprintln(buff, "# How much graph history a daemon keeps, as a duration")
+	fmt.Fprintf(buff, "%s=%s\n", daemonhistory, c.DaemonHistory)
+	fmt.Fprintln(buff, "# The units of sizes and rates: iec for powers of 1024 (KiB, MiB), or si for")
+	fmt.Fprintln(buff, "# powers of 1000 (kB, MB)")
+	fmt.Fprintf(buff, "%s=%s\n", units, c.Units.Prefixes())
+	fmt.Fprintln(buff, "# Show network rates in bits per second if true, e.g. Mib/s, rather than bytes")
+	fmt.Fprintf(buff, "%s=%t\n", bits, c.Units.Bits)
+	fmt.Fprintln(buff, "# Show network packets, errors, and drops per second if true")
+	fmt.Fprintf(buff, "%s=%t\n", netpackets, c.NetPackets)
+	fmt.Fprintln(buff, "# Scale the network and disk IO graphs logarithmically, so that bursts don't")
+	fmt.Fprintln(buff, "# flatten the rest of the graph")
+	fmt.Fprintf(buff, "%s=%t\n", sparklinelog, c.SparklineLog)
+	fmt.Fprintln(buff, "# Scale all the lines of those graphs alike, e.g. RX and TX, so they can be compared")
+	fmt.Fprintf(buff, "%s=%t\n", sparklineshared, c.SparklineShared)
+	fmt.Fprintln(buff, "# Show the value of a full bar in those graphs")
+	fmt.Fprintf(buff, "%s=%t\n", sparklinelegend, c.SparklineLegend)
+	fmt.Fprintln(buff, "# Ring the bell and flash the widget when memory or a disk is nearly full, a")
+	fmt.Fprintln(buff, "# battery is nearly empty, or a sensor reaches its critical temperature")
+	fmt.Fprintf(buff, "%s=%t\n", bell, c.Bell)
 	fmt.Fprintln(buff, "# A list of enabled temp sensors.  See `--list devices`")
 	if len(c.Temps) == 0 {
 		fmt.Fprint(buff, "#")
 	}
 	fmt.Fprintf(buff, "%s=%s\n", temperatures, strings.Join(c.Temps, ","))
+	fmt.Fprintln(buff, "# Disks shown by the disk widget, as comma separated patterns of devices, mount")
+	fmt.Fprintln(buff, "# points, and filesystem types. * matches anything, and patterns starting with")
+	fmt.Fprintln(buff, "# ! exclude. See `--list devices`")
+	for _, l := range []struct {
+		key      string
+		patterns []string
+	}{{diskdevices, c.Disks.Devices}, {diskmounts, c.Disks.Mounts}, {diskfstypes, c.Disks.Fstypes}} {
+		if len(l.patterns) == 0 {
+			fmt.Fprint(buff, "#")
+		}
+		fmt.Fprintf(buff, "%s=%s\n", l.key, strings.Join(l.patterns, ","))
+	}
+	fmt.Fprintln(buff, "# If true, also show pseudo filesystems, like tmpfs")
+	fmt.Fprintf(buff, "%s=%t\n", diskpseudo, c.Disks.Pseudo)
+	fmt.Fprintln(buff, "# Extra disk widget columns: inodes (the percentage used), fstype, and total")
+	if len(c.DiskColumns) == 0 {
+		fmt.Fprint(buff, "#")
+	}
+	fmt.Fprintf(buff, "%s=%s\n", diskcolumns, strings.Join(c.DiskColumns, ","))
+	fmt.Fprintln(buff, "# Colour disks whose space or inodes are at least this percent used; 0 for never")
+	fmt.Fprintf(buff, "%s=%d\n", diskwarn, c.DiskWarn)
 	fmt.Fprintln(buff, "# Enable NVidia GPU metrics.")
 	fmt.Fprintf(buff, "%s=%t\n", nvidia, c.Nvidia)
 	fmt.Fprintln(buff, "# To configure the NVidia refresh rate, set a duration:")
 	fmt.Fprintln(buff, "#nvidiarefresh=30s")
+	fmt.Fprintln(buff, "# Alerts, as alert-NAME-rule=DOMAIN.KEY OP VALUE [for DURATION]. See docs/alerts.md")
+	var alerts []string
+	for k := range c.ExtensionVars {
+		if strings.HasPrefix(k, "alert-") {
+			alerts = append(alerts, k)
+		}
+	}
+	sort.Strings(alerts)
+	if len(alerts) == 0 {
+		fmt.Fprintln(buff, "#alert-busy-rule=cpu.avg > 90 for 30s")
+	}
+	for _, k := range alerts {
+		fmt.Fprintf(buff, "%s=%s\n", k, c.ExtensionVars[k])
+	}
 	return buff.Bytes()
 }
 
+// MetricsEnabled is true if metrics are exported in any way, in which case
+// widgets must register their metrics. UIs attached to a daemon never export
+// metrics; the daemon does.
+func (conf *Config) MetricsEnabled() bool {
+	if conf.Attach {
+		return false
+	}
+	return conf.ExportPort != "" || conf.PushURL != "" || conf.InfluxURL != "" || conf.StatsdAddr != "" || conf.SampleLog != ""
+}
+
+// splitList splits a comma separated list, dropping empty items, so that an
+// empty value is an empty list.
+func splitList(v string) []string {
+	var rv []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			rv = append(rv, s)
+		}
+	}
+	return rv
+}
+
+// commentIfEmpty comments out the next line written to buff if v is unset.
+func commentIfEmpty(buff *bytes.Buffer, v string) {
+	if v == "" {
+		fmt.Fprint(buff, "#")
+	}
+}
+
 const (
 	graphhorizontalscale = "graphhorizontalscale"
 	helpvisible          = "helpvisible"
@@ -289,8 +569,40 @@ const (
 	layout               = "layout"
 	maxlogsize           = "maxlogsize"
 	export               = "metricsexportport"
+	exporttlscert        = "metricstlscert"
+	exporttlskey         = "metricstlskey"
+	exportuser           = "metricsuser"
+	exportpassword       = "metricspassword"
+	exporttoken          = "metricstoken"
+	pushurl              = "pushurl"
+	pushformat           = "pushformat"
+	pushjob              = "pushjob"
+	pushinstance         = "pushinstance"
+	pushinterval         = "pushinterval"
+	influxurl            = "influxurl"
+	influxtoken          = "influxtoken"
+	statsdaddr           = "statsdaddr"
+	statsdprefix         = "statsdprefix"
+	samplelog            = "samplelog"
+	samplelogsplit       = "samplelogsplit"
+	samplelogsize        = "samplelogsize"
+	socket               = "socket"
+	daemonhistory        = "daemonhistory"
+	units                = "units"
+	bits                 = "bits"
 	mbps                 = "mbps"
+	netpackets           = "netpackets"
+	sparklinelog         = "sparklinelog"
+	sparklineshared      = "sparklineshared"
+	sparklinelegend      = "sparklinelegend"
+	bell                 = "bell"
 	temperatures         = "temperatures"
+	diskdevices          = "diskdevices"
+	diskmounts           = "diskmounts"
+	diskfstypes          = "diskfstypes"
+	diskpseudo           = "diskpseudo"
+	diskcolumns          = "diskcolumns"
+	diskwarn             = "diskwarn"
 	nvidia               = "nvidia"
 	nvidiarefresh        = "nvidiarefresh"
 )
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/xxxserxxx/gotop/v4/utils"
 	"github.com/xxxserxxx/gotop/v4/widgets"
 )
 
@@ -60,6 +61,79 @@ func TestParse(t *testing.T) {
 				assert.Equal(t, int64(200), c.MaxLogSize)
 			},
 		},
+		{
+			i: "diskdevices=/dev/sd*, !/dev/sdb*\ndiskmounts=\ndiskfstypes=ext4,xfs\ndiskpseudo=true",
+			f: func(c Config, e error) {
+				assert.Nil(t, e, "unexpected error")
+				assert.Equal(t, []string{"/dev/sd*", "!/dev/sdb*"}, c.Disks.Devices)
+				assert.Empty(t, c.Disks.Mounts)
+				assert.Equal(t, []string{"ext4", "xfs"}, c.Disks.Fstypes)
+				assert.True(t, c.Disks.Pseudo)
+			},
+		},
+		{
+			i: "diskcolumns=inodes,total\ndiskwarn=80",
+			f: func(c Config, e error) {
+				assert.Nil(t, e, "unexpected error")
+				assert.Equal(t, []string{"inodes", "total"}, c.DiskColumns)
+				assert.Equal(t, 80, c.DiskWarn)
+			},
+		},
+		{
+			i: "netpackets=true",
+			f: func(c Config, e error) {
+				assert.Nil(t, e, "unexpected error")
+				assert.True(t, c.NetPackets)
+			},
+		},
+		{
+			i: "sparklinelog=true\nsparklineshared=false\nsparklinelegend=true",
+			f: func(c Config, e error) {
+				assert.Nil(t, e, "unexpected error")
+				assert.True(t, c.SparklineLog)
+				assert.False(t, c.SparklineShared)
+				assert.True(t, c.SparklineLegend)
+			},
+		},
+		{
+			i: "units=si\nbits=true",
+			f: func(c Config, e error) {
+				assert.Nil(t, e, "unexpected error")
+				assert.Equal(t, utils.Units{SI: true, Bits: true}, c.Units)
+			},
+		},
+		{
+			i: "units=IEC\nmbps=true",
+			f: func(c Config, e error) {
+				assert.Nil(t, e, "unexpected error")
+				assert.Equal(t, utils.Units{Bits: true}, c.Units)
+			},
+		},
+		{
+			i: "mbps=false",
+			f: func(c Config, e error) {
+				assert.Nil(t, e, "unexpected error")
+				assert.False(t, c.Units.Bits)
+			},
+		},
+		{
+			i: "units=metric",
+			f: func(c Config, e error) {
+				assert.Error(t, e, "expected invalid units")
+			},
+		},
+		{
+			i: "netpackets=sometimes",
+			f: func(c Config, e error) {
+				assert.Error(t, e, "expected invalid boolean")
+			},
+		},
+		{
+			i: "diskcolumns=inodes,size",
+			f: func(c Config, e error) {
+				assert.Error(t, e, "expected invalid disk column")
+			},
+		},
 	}
 	for _, tc := range tests {
 		in := strings.NewReader(tc.i)
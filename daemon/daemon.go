@@ -0,0 +1,198 @@
+// Package daemon shares the data collected by one gotop with any number of
+// UIs over a Unix socket.
+//
+// A daemon builds the widgets of its layout without displaying them, so
+// collection (and history) continues while no UI is attached. Attached UIs
+// build the daemon's layout with passive widgets and periodically copy the
+// daemon's widget state into them.
+package daemon
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"os"
+	"time"
+
+	"github.com/xxxserxxx/gotop/v4/widgets"
+)
+
+// Request is sent by an attached UI to ask for the daemon's state.
+type Request struct {
+	// Points is the largest number of data points per graph the UI can use.
+	Points int
+}
+
+// Snapshot is the daemon's reply to a Request.
+type Snapshot struct {
+	// Layout is the layout specification the daemon was started with.
+	Layout string
+	// Interval is the daemon's update interval.
+	Interval time.Duration
+	// Widgets holds the state of each widget, in layout order.
+	Widgets []widgets.State
+}
+
+// Server serves snapshots of widgets to attached UIs.
+type Server struct {
+	layout   string
+	interval time.Duration
+	widgets  []widgets.Stateful
+	history  time.Duration
+	listener net.Listener
+}
+
+// Listen creates the socket at path. A stale socket left by a daemon that
+// didn't exit cleanly is replaced, but a socket that is in use is an error.
+// Widgets keep history worth of data points per graph, however often they're
+// updated.
+func Listen(path, layout string, interval time.Duration, ws []widgets.Stateful, history time.Duration) (*Server, error) {
+	if _, err := os.Stat(path); err == nil {
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("a daemon is already listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// Anyone who can reach the socket may attach; access is controlled by
+	// the permissions of the directory it's in.
+	if err := os.Chmod(path, 0666); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return &Server{
+		layout:   layout,
+		interval: interval,
+		widgets:  ws,
+		history:  history,
+		listener: l,
+	}, nil
+}
+
+// Serve accepts connections until the server is closed.
+func (s *Server) Serve() error {
+	go func() {
+		for range time.NewTicker(time.Minute).C {
+			s.trim()
+		}
+	}()
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(c)
+	}
+}
+
+// Close stops the server and removes the socket.
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+func (s *Server) handle(c net.Conn) {
+	defer c.Close()
+	dec := gob.NewDecoder(c)
+	enc := gob.NewEncoder(c)
+	for {
+		var req Request
+		if err := dec.Decode(&req); err != nil {
+			if err != io.EOF {
+				log.Printf("daemon: %s", err)
+			}
+			return
+		}
+		if err := enc.Encode(s.snapshot(req.Points)); err != nil {
+			log.Printf("daemon: %s", err)
+			return
+		}
+	}
+}
+
+// snapshot copies the widgets' state, with at most points data points per
+// graph, or all of the history if points isn't positive.
+func (s *Server) snapshot(points int) Snapshot {
+	if points <= 0 {
+		points = math.MaxInt32
+	}
+	snap := Snapshot{
+		Layout:   s.layout,
+		Interval: s.interval,
+		Widgets:  make([]widgets.State, len(s.widgets)),
+	}
+	for i, w := range s.widgets {
+		w.Lock()
+		w.Trim(s.history)
+		snap.Widgets[i] = w.Snapshot(points)
+		w.Unlock()
+	}
+	return snap
+}
+
+// trim discards history the daemon doesn't need to keep. Widgets in a UI
+// don't need this, because they're trimmed to fit when they're drawn.
+func (s *Server) trim() {
+	for _, w := range s.widgets {
+		w.Lock()
+		w.Trim(s.history)
+		w.Unlock()
+	}
+}
+
+// Client is a connection from a UI to a daemon.
+type Client struct {
+	conn net.Conn
+	enc  *gob.Encoder
+	dec  *gob.Decoder
+}
+
+// Dial connects to the daemon listening on path.
+func Dial(path string) (*Client, error) {
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: c, enc: gob.NewEncoder(c), dec: gob.NewDecoder(c)}, nil
+}
+
+// Fetch gets the daemon's state, with at most points data points per graph.
+func (c *Client) Fetch(points int) (Snapshot, error) {
+	var snap Snapshot
+	c.conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if err := c.enc.Encode(Request{Points: points}); err != nil {
+		return snap, err
+	}
+	err := c.dec.Decode(&snap)
+	return snap, err
+}
+
+// Close disconnects from the daemon.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Apply copies the state in a snapshot into widgets, which must have been
+// created from the snapshot's layout.
+func Apply(ws []widgets.Stateful, snap Snapshot) {
+	for i, w := range ws {
+		if i >= len(snap.Widgets) {
+			break
+		}
+		w.Lock()
+		w.Restore(snap.Widgets[i])
+		w.Unlock()
+	}
+}
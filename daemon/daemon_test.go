@@ -0,0 +1,113 @@
+package daemon
+
+import (
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xxxserxxx/gotop/v4/widgets"
+)
+
+type fakeWidget struct {
+	sync.Mutex
+	data []float64
+	// interval is how often it's updated; a second if it's 0
+	interval time.Duration
+}
+
+func (f *fakeWidget) Snapshot(points int) widgets.State {
+	d := f.data
+	if len(d) > points {
+		d = d[len(d)-points:]
+	}
+	return widgets.State{Series: map[string][]float64{"x": d}}
+}
+
+func (f *fakeWidget) Restore(s widgets.State) {
+	f.data = s.Series["x"]
+}
+
+func (f *fakeWidget) Trim(history time.Duration) {
+	interval := f.interval
+	if interval == 0 {
+		interval = time.Second
+	}
+	points := int(history / interval)
+	if len(f.data) > points {
+		f.data = f.data[len(f.data)-points:]
+	}
+}
+
+func TestAttach(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gotop.sock")
+	src := []widgets.Stateful{
+		&fakeWidget{data: []float64{1, 2, 3, 4, 5}},
+		&fakeWidget{data: []float64{6}},
+	}
+	srv, err := Listen(path, "cpu\nmem", time.Second, src, 4*time.Second)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer srv.Close()
+	go srv.Serve()
+
+	_, err = Listen(path, "cpu", time.Second, src, 4*time.Second)
+	assert.Error(t, err, "a second daemon on the same socket")
+
+	c, err := Dial(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	tests := []struct {
+		points int
+		want   [][]float64
+	}{
+		{0, [][]float64{{2, 3, 4, 5}, {6}}},
+		{2, [][]float64{{4, 5}, {6}}},
+		{10, [][]float64{{2, 3, 4, 5}, {6}}},
+	}
+	for _, tc := range tests {
+		snap, err := c.Fetch(tc.points)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "cpu\nmem", snap.Layout)
+		assert.Equal(t, time.Second, snap.Interval)
+		dst := []widgets.Stateful{&fakeWidget{}, &fakeWidget{}}
+		Apply(dst, snap)
+		for i, w := range dst {
+			assert.Equal(t, tc.want[i], w.(*fakeWidget).data, "points=%d widget %d", tc.points, i)
+		}
+	}
+
+	srv.trim()
+	assert.Equal(t, []float64{2, 3, 4, 5}, src[0].(*fakeWidget).data)
+
+	// A widget that's updated less often keeps fewer points
+	slow := &fakeWidget{data: []float64{1, 2, 3, 4, 5}, interval: 2 * time.Second}
+	srv.widgets = append(srv.widgets, slow)
+	srv.trim()
+	assert.Equal(t, []float64{4, 5}, slow.data)
+}
+
+func TestStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gotop.sock")
+	l, err := net.Listen("unix", path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// Leave the socket file behind, as a crashed daemon would
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	srv, err := Listen(path, "cpu", time.Second, nil, time.Second)
+	if assert.NoError(t, err) {
+		srv.Close()
+	}
+}
@@ -0,0 +1,219 @@
+package devices
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// procRoot is where Connections reads the sockets and processes from.
+var procRoot = "/proc"
+
+// ConnProtos are the socket tables read from procfs, which are also the
+// protocols of the connections.
+var ConnProtos = []string{"tcp", "tcp6", "udp", "udp6"}
+
+// Connection states, as in the kernel's tcp_states.h. UDP sockets are either
+// ESTABLISHED, if connected, or CLOSE, which is shown as UNCONN, like ss does.
+var connStates = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+	"0C": "NEW_SYN_RECV",
+}
+
+// ConnUnconnected is the state of UDP sockets that aren't connected, which
+// includes those that are listening.
+const ConnUnconnected = "UNCONN"
+
+// Conn is a socket. Pid is 0, and Process empty, if the owner is unknown,
+// usually because it belongs to another user and gotop isn't running as root.
+type Conn struct {
+	Proto      string
+	LocalAddr  net.IP
+	LocalPort  int
+	RemoteAddr net.IP
+	RemotePort int
+	State      string
+	Inode      uint64
+	Pid        int
+	Process    string
+}
+
+// Local is the local address and port of the connection.
+func (c Conn) Local() string {
+	return net.JoinHostPort(c.LocalAddr.String(), strconv.Itoa(c.LocalPort))
+}
+
+// Remote is the remote address and port of the connection.
+func (c Conn) Remote() string {
+	return net.JoinHostPort(c.RemoteAddr.String(), strconv.Itoa(c.RemotePort))
+}
+
+// Listening is true for TCP sockets that are listening, and UDP sockets that
+// are bound but not connected.
+func (c Conn) Listening() bool {
+	if strings.HasPrefix(c.Proto, "udp") {
+		return c.State == ConnUnconnected
+	}
+	return c.State == "LISTEN"
+}
+
+// Connections lists the local TCP and UDP sockets, with their owners. It's
+// empty on systems without a Linux procfs.
+func Connections() ([]Conn, error) {
+	return readConnections(procRoot)
+}
+
+func readConnections(root string) ([]Conn, error) {
+	var conns []Conn
+	for _, proto := range ConnProtos {
+		f, err := os.Open(filepath.Join(root, "net", proto))
+		if err != nil {
+			// Missing if IPv6 is disabled, or there's no procfs
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		cs, err := parseProcNet(f, proto)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, cs...)
+	}
+	if len(conns) == 0 {
+		return conns, nil
+	}
+	owners := socketOwners(root)
+	for i, c := range conns {
+		if o, ok := owners[c.Inode]; ok {
+			conns[i].Pid, conns[i].Process = o.pid, o.name
+		}
+	}
+	return conns, nil
+}
+
+// parseProcNet parses a socket table, such as /proc/net/tcp, whose lines are
+//
+//	sl  local_address rem_address   st tx_queue:rx_queue tr:tm->when retrnsmt   uid  timeout inode
+//	 0: 0100007F:0277 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 21367 ...
+func parseProcNet(r io.Reader, proto string) ([]Conn, error) {
+	var conns []Conn
+	s := bufio.NewScanner(r)
+	for ln := 1; s.Scan(); ln++ {
+		fs := strings.Fields(s.Text())
+		if ln == 1 || len(fs) == 0 {
+			continue
+		}
+		if len(fs) < 10 {
+			return nil, fmt.Errorf("%s line %d: too few fields", proto, ln)
+		}
+		c := Conn{Proto: proto}
+		var err error
+		if c.LocalAddr, c.LocalPort, err = parseSocketAddr(fs[1]); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", proto, ln, err)
+		}
+		if c.RemoteAddr, c.RemotePort, err = parseSocketAddr(fs[2]); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", proto, ln, err)
+		}
+		c.State = connStates[strings.ToUpper(fs[3])]
+		if c.State == "" {
+			c.State = fs[3]
+		}
+		if strings.HasPrefix(proto, "udp") && c.State == "CLOSE" {
+			c.State = ConnUnconnected
+		}
+		if c.Inode, err = strconv.ParseUint(fs[9], 10, 64); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", proto, ln, err)
+		}
+		conns = append(conns, c)
+	}
+	return conns, s.Err()
+}
+
+// parseSocketAddr parses an address of a socket table, which is the hex IP
+// followed by the hex port. The IP is printed as 32 bit words in host byte
+// order, which is assumed to be little endian.
+func parseSocketAddr(s string) (net.IP, int, error) {
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return nil, 0, fmt.Errorf("bad address %q", s)
+	}
+	ip, err := hex.DecodeString(s[:i])
+	if err != nil || (len(ip) != net.IPv4len && len(ip) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("bad address %q", s)
+	}
+	for w := 0; w < len(ip); w += 4 {
+		ip[w], ip[w+1], ip[w+2], ip[w+3] = ip[w+3], ip[w+2], ip[w+1], ip[w]
+	}
+	port, err := strconv.ParseUint(s[i+1:], 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("bad port %q", s)
+	}
+	return net.IP(ip), int(port), nil
+}
+
+type socketOwner struct {
+	pid  int
+	name string
+}
+
+// socketOwners maps socket inodes to the processes that have them open. The
+// file descriptors of other users' processes can only be read by root, so
+// their sockets are left out.
+func socketOwners(root string) map[uint64]socketOwner {
+	owners := make(map[uint64]socketOwner)
+	ds, err := ioutil.ReadDir(root)
+	if err != nil {
+		return owners
+	}
+	for _, d := range ds {
+		pid, err := strconv.Atoi(d.Name())
+		if err != nil || !d.IsDir() {
+			continue
+		}
+		fdDir := filepath.Join(root, d.Name(), "fd")
+		fds, err := ioutil.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		var name string
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			if name == "" {
+				bs, _ := ioutil.ReadFile(filepath.Join(root, d.Name(), "comm"))
+				name = strings.TrimSpace(string(bs))
+			}
+			// Sockets shared by a parent and its children usually go to the
+			// parent, which has the lower PID
+			if o, ok := owners[inode]; !ok || pid < o.pid {
+				owners[inode] = socketOwner{pid, name}
+			}
+		}
+	}
+	return owners
+}
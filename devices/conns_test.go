@@ -0,0 +1,56 @@
+package devices
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadConnections(t *testing.T) {
+	conns, err := readConnections("testdata/proc")
+	assert.NoError(t, err)
+	type row struct {
+		proto, local, remote, state string
+		pid                         int
+		process                     string
+		listening                   bool
+	}
+	var rows []row
+	for _, c := range conns {
+		rows = append(rows, row{c.Proto, c.Local(), c.Remote(), c.State, c.Pid, c.Process, c.Listening()})
+	}
+	assert.Equal(t, []row{
+		{"tcp", "0.0.0.0:22", "0.0.0.0:0", "LISTEN", 1234, "sshd", true},
+		{"tcp", "127.0.0.1:8080", "0.0.0.0:0", "LISTEN", 1300, "python3", true},
+		{"tcp", "10.0.2.15:22", "10.0.2.2:54258", "ESTABLISHED", 1234, "sshd", false},
+		{"tcp", "10.0.2.15:40000", "93.184.216.34:443", "TIME_WAIT", 0, "", false},
+		{"tcp6", "[::]:22", "[::]:0", "LISTEN", 1234, "sshd", true},
+		{"tcp6", "127.0.0.1:8080", "127.0.0.1:57812", "ESTABLISHED", 1300, "python3", false},
+		{"udp", "127.0.0.53:53", "0.0.0.0:0", "UNCONN", 0, "", true},
+		{"udp", "10.0.2.15:41394", "8.8.8.8:53", "ESTABLISHED", 0, "", false},
+	}, rows)
+}
+
+func TestParseProcNet(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		err  bool
+	}{
+		{"header only", "  sl  local_address rem_address   st\n", false},
+		{"short line", "header\n   0: 00000000:0016 00000000:0000 0A\n", true},
+		{"bad address", "header\n   0: 000000:0016 00000000:0000 0A 0 0 0 0 0 1\n", true},
+		{"bad port", "header\n   0: 00000000:XYZ 00000000:0000 0A 0 0 0 0 0 1\n", true},
+		{"bad inode", "header\n   0: 00000000:0016 00000000:0000 0A 0 0 0 0 0 x\n", true},
+	}
+	for _, tc := range tests {
+		conns, err := parseProcNet(strings.NewReader(tc.in), "tcp")
+		if tc.err {
+			assert.Error(t, err, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+			assert.Empty(t, conns, tc.name)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package devices
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	psDisk "github.com/shirou/gopsutil/v3/disk"
+
+	"github.com/xxxserxxx/gotop/v4/utils"
+)
+
+// DiskFilter selects the partitions shown by disk widgets. Each list holds
+// utils.Glob patterns; a partition is excluded if it matches a pattern
+// starting with !, and if a list has other patterns, it must match one of
+// them.
+type DiskFilter struct {
+	Devices []string
+	Mounts  []string
+	Fstypes []string
+	// Pseudo includes filesystems without a device, like tmpfs
+	Pseudo bool
+}
+
+// DefaultDiskFilter hides loop devices and docker container filesystems.
+var DefaultDiskFilter = DiskFilter{
+	Devices: []string{"!/dev/loop*"},
+	Mounts:  []string{"!/var/lib/docker/*"},
+}
+
+var _diskFilter = DefaultDiskFilter
+
+// SetDiskFilter sets the filter used by Partitions.
+func SetDiskFilter(f DiskFilter) {
+	_diskFilter = f
+}
+
+// Partitions returns the mounted partitions that pass the disk filter, and
+// those that don't.
+func Partitions() (included, excluded []psDisk.PartitionStat, err error) {
+	ps, err := psDisk.Partitions(_diskFilter.Pseudo)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _diskFilter.Pseudo {
+		// Without a size, pseudo filesystems like proc have nothing to show
+		sized := ps[:0]
+		for _, p := range ps {
+			if u, err := psDisk.Usage(p.Mountpoint); err == nil && u.Total > 0 {
+				sized = append(sized, p)
+			}
+		}
+		ps = sized
+	}
+	included, excluded = _diskFilter.Filter(ps)
+	return included, excluded, nil
+}
+
+// Filter splits partitions into those that pass the filter and those that
+// don't. Bind mounts of a device that's already included are excluded,
+// keeping the mount with the shortest path.
+func (f DiskFilter) Filter(ps []psDisk.PartitionStat) (included, excluded []psDisk.PartitionStat) {
+	mounted := make(map[string]int)
+	for _, p := range ps {
+		if !f.Wanted(p) {
+			excluded = append(excluded, p)
+			continue
+		}
+		// Only real devices can be bind mounted; tmpfs and friends all share
+		// a device name
+		if strings.HasPrefix(p.Device, "/") {
+			if i, ok := mounted[p.Device]; ok {
+				if len(p.Mountpoint) < len(included[i].Mountpoint) {
+					p, included[i] = included[i], p
+				}
+				excluded = append(excluded, p)
+				continue
+			}
+			mounted[p.Device] = len(included)
+		}
+		included = append(included, p)
+	}
+	return included, excluded
+}
+
+// Wanted reports whether the partition passes the device, mount, and
+// filesystem type patterns.
+func (f DiskFilter) Wanted(p psDisk.PartitionStat) bool {
+	return utils.GlobFilter(f.Devices, p.Device) &&
+		utils.GlobFilter(f.Mounts, p.Mountpoint) &&
+		utils.GlobFilter(f.Fstypes, p.Fstype)
+}
+
+// DiskIO returns the IO counters of the disks that pass the device filter,
+// keyed by name, e.g. sda. On Linux, partitions are left out, as their IO is
+// included in their disk's; so are disks that have never been used, like
+// unused loop and ram devices.
+func DiskIO() (map[string]psDisk.IOCountersStat, error) {
+	cs, err := psDisk.IOCounters()
+	if err != nil {
+		return nil, err
+	}
+	_, err = os.Stat("/sys/block")
+	whole := err == nil
+	for name, c := range cs {
+		if c.ReadCount+c.WriteCount == 0 || !utils.GlobFilter(_diskFilter.Devices, "/dev/"+name) {
+			delete(cs, name)
+			continue
+		}
+		if whole {
+			if _, err := os.Stat(filepath.Join("/sys/block", name)); err != nil {
+				delete(cs, name)
+			}
+		}
+	}
+	return cs, nil
+}
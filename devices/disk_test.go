@@ -0,0 +1,57 @@
+package devices
+
+import (
+	"testing"
+
+	psDisk "github.com/shirou/gopsutil/v3/disk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskFilter(t *testing.T) {
+	ps := []psDisk.PartitionStat{
+		{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4"},
+		{Device: "/dev/loop0", Mountpoint: "/snap/core/1", Fstype: "squashfs"},
+		{Device: "/dev/sda2", Mountpoint: "/var/lib/docker/overlay2", Fstype: "ext4"},
+		{Device: "/dev/sdb1", Mountpoint: "/srv/data/export", Fstype: "xfs"},
+		{Device: "/dev/sdb1", Mountpoint: "/srv/data", Fstype: "xfs"},
+		{Device: "tmpfs", Mountpoint: "/tmp", Fstype: "tmpfs"},
+		{Device: "tmpfs", Mountpoint: "/run", Fstype: "tmpfs"},
+	}
+	mounts := func(ps []psDisk.PartitionStat) []string {
+		var rv []string
+		for _, p := range ps {
+			rv = append(rv, p.Mountpoint)
+		}
+		return rv
+	}
+	tests := []struct {
+		name     string
+		filter   DiskFilter
+		included []string
+		excluded []string
+	}{
+		{
+			name:     "default",
+			filter:   DefaultDiskFilter,
+			included: []string{"/", "/srv/data", "/tmp", "/run"},
+			excluded: []string{"/snap/core/1", "/var/lib/docker/overlay2", "/srv/data/export"},
+		},
+		{
+			name:     "fstypes",
+			filter:   DiskFilter{Fstypes: []string{"ext*", "xfs"}},
+			included: []string{"/", "/var/lib/docker/overlay2", "/srv/data"},
+			excluded: []string{"/snap/core/1", "/srv/data/export", "/tmp", "/run"},
+		},
+		{
+			name:     "devices",
+			filter:   DiskFilter{Devices: []string{"/dev/sd*", "!/dev/sdb*"}, Fstypes: []string{"!tmpfs"}},
+			included: []string{"/", "/var/lib/docker/overlay2"},
+			excluded: []string{"/snap/core/1", "/srv/data/export", "/srv/data", "/tmp", "/run"},
+		},
+	}
+	for _, tc := range tests {
+		included, excluded := tc.filter.Filter(ps)
+		assert.Equal(t, tc.included, mounts(included), tc.name)
+		assert.ElementsMatch(t, tc.excluded, mounts(excluded), tc.name)
+	}
+}
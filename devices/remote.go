@@ -2,9 +2,13 @@ package devices
 
 import (
 	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
-	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,7 +25,6 @@ var remoteLock sync.Mutex
 
 // FIXME Widgets don't align values
 // TODO remote network & disk aren't reported
-// TODO network resiliency; I believe it currently crashes gotop when the network goes down
 // TODO Replace custom decoder with https://github.com/prometheus/common/blob/master/expfmt/decode.go
 // FIXME high CPU use when remote goes offline
 // FIXME higher CPU use when using remote in general
@@ -34,16 +37,69 @@ func init() {
 }
 
 type Remote struct {
-	url     string
-	refresh time.Duration
+	url      string
+	refresh  time.Duration
+	user     string
+	password string
+	token    string
+	ca       string
+	client   *http.Client
+}
+
+// connect prepares the HTTP client used to poll the remote, loading the CA
+// certificate if one is configured. A request carries one Authorization
+// header, so a remote can't have both a user and a token.
+func (r *Remote) connect() error {
+	if r.user != "" && r.token != "" {
+		return fmt.Errorf("both a user and a token are configured; use only one")
+	}
+	r.client = &http.Client{Timeout: 10 * time.Second}
+	if r.ca == "" {
+		return nil
+	}
+	pem, err := ioutil.ReadFile(r.ca)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found in %s", r.ca)
+	}
+	r.client.Transport = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}
+	return nil
+}
+
+// poll fetches the remote's metrics and caches them under name.
+func (r *Remote) poll(name string) error {
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+	if r.user != "" {
+		req.SetBasicAuth(r.user, r.password)
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		u := *req.URL
+		u.User = nil
+		return fmt.Errorf("unsuccessful connection to %s: http status %s", u.String(), res.Status)
+	}
+	process(name, bufio.NewScanner(res.Body))
+	return nil
 }
 
 func startup(vars map[string]string) error {
-	_cpuData = make(map[string]int)
-	_tempData = make(map[string]int)
-	_netData = make(map[string]float64)
-	_diskData = make(map[string]float64)
-	_memData = make(map[string]MemoryInfo)
+	_hosts = make(map[string]*remoteHost)
 
 	remoteLock = sync.Mutex{}
 	remotes := parseConfig(vars)
@@ -75,10 +131,6 @@ func startup(vars map[string]string) error {
 		return nil
 	}
 
-	RegisterTemp(updateTemp)
-	RegisterMem(updateMem)
-	RegisterCPU(updateUsage)
-
 	// We need to know what we're dealing with, so the following code does two
 	// things, one of them sneakily. It forks off background processes
 	// to periodically pull data from remote sources and cache the results for
@@ -89,28 +141,20 @@ func startup(vars map[string]string) error {
 	// the wait group.
 	w := &sync.WaitGroup{}
 	for n, r := range remotes {
-		n = n + "-"
-		var u *url.URL
+		if r.refresh == 0 {
+			r.refresh = time.Second
+		}
+		if err := r.connect(); err != nil {
+			log.Printf("Remote: disabling %s: %s", n, err)
+			continue
+		}
 		w.Add(1)
 		go func(name string, remote Remote, wg *sync.WaitGroup) {
 			for {
-				res, err := http.Get(remote.url)
-				if err == nil {
-					u, err = url.Parse(remote.url)
-					if err == nil {
-						if res.StatusCode == http.StatusOK {
-							bi := bufio.NewScanner(res.Body)
-							process(name, bi)
-						} else {
-							u.User = nil
-							log.Printf("unsuccessful connection to %s: http status %s", u.String(), res.Status)
-						}
-					} else {
-						log.Print("error processing remote URL")
-					}
-				} else {
+				if err := remote.poll(name); err != nil {
+					log.Printf("Remote: %s", err)
+					failed(name, err)
 				}
-				res.Body.Close()
 				if wg != nil {
 					wg.Done()
 					wg = nil
@@ -123,117 +167,292 @@ func startup(vars map[string]string) error {
 	return nil
 }
 
-var (
-	_cpuData  map[string]int
-	_tempData map[string]int
-	_netData  map[string]float64
-	_diskData map[string]float64
-	_memData  map[string]MemoryInfo
-)
+// remoteHost holds the most recent data polled from a single remote.
+type remoteHost struct {
+	cpu  map[string]int
+	temp map[string]int
+	disk map[string]float64
+	mem  map[string]MemoryInfo
+	batt map[string]float64
+	// net holds the byte counters; rates are calculated between polls
+	net     map[string]float64
+	rates   map[string]float64
+	polled  time.Time
+	lastErr error
+}
 
-func process(host string, data *bufio.Scanner) {
+func newRemoteHost() *remoteHost {
+	return &remoteHost{
+		cpu:   make(map[string]int),
+		temp:  make(map[string]int),
+		disk:  make(map[string]float64),
+		mem:   make(map[string]MemoryInfo),
+		batt:  make(map[string]float64),
+		net:   make(map[string]float64),
+		rates: make(map[string]float64),
+	}
+}
+
+// _hosts is keyed by the remote name, and guarded by remoteLock
+var _hosts map[string]*remoteHost
+
+// RemoteHost is a summary of the most recent data from a remote gotop.
+type RemoteHost struct {
+	Name string
+	// CPU is the average load of all of the remote's CPUs, in percent
+	CPU float64
+	// Mem is the percentage of main memory used
+	Mem float64
+	// MaxTemp is the highest temperature reported by any sensor
+	MaxTemp int
+	// RecvRate and SentRate are the network rates, in bytes per second
+	RecvRate float64
+	SentRate float64
+	// Reachable is false if the last poll of the remote failed
+	Reachable bool
+	// LastSeen is the time of the last successful poll
+	LastSeen time.Time
+}
+
+// RemoteHosts returns a summary of each configured remote, sorted by name.
+func RemoteHosts() []RemoteHost {
 	remoteLock.Lock()
+	defer remoteLock.Unlock()
+	rv := make([]RemoteHost, 0, len(_hosts))
+	for name, h := range _hosts {
+		rh := RemoteHost{
+			Name:      name,
+			RecvRate:  h.rates["recv"],
+			SentRate:  h.rates["sent"],
+			Reachable: h.lastErr == nil && !h.polled.IsZero(),
+			LastSeen:  h.polled,
+		}
+		if len(h.cpu) > 0 {
+			var sum int
+			for _, v := range h.cpu {
+				sum += v
+			}
+			rh.CPU = float64(sum) / float64(len(h.cpu))
+		}
+		if m, ok := h.mem["Main"]; ok {
+			rh.Mem = m.UsedPercent
+		}
+		for _, t := range h.temp {
+			if t > rh.MaxTemp {
+				rh.MaxTemp = t
+			}
+		}
+		rv = append(rv, rh)
+	}
+	sort.Slice(rv, func(i, j int) bool { return rv[i].Name < rv[j].Name })
+	return rv
+}
+
+// failed records an unsuccessful poll of the named remote. Its values are
+// zeroed, so that widgets don't keep showing the last data of a remote that
+// can't be reached; the devices are kept, as are the network counters, from
+// which rates are calculated once the remote is back.
+func failed(name string, err error) {
+	remoteLock.Lock()
+	defer remoteLock.Unlock()
+	h, ok := _hosts[name]
+	if !ok {
+		h = newRemoteHost()
+		_hosts[name] = h
+	}
+	h.lastErr = err
+	for k := range h.cpu {
+		h.cpu[k] = 0
+	}
+	for k := range h.temp {
+		h.temp[k] = 0
+	}
+	for k := range h.mem {
+		h.mem[k] = MemoryInfo{Total: 100}
+	}
+	for k := range h.disk {
+		h.disk[k] = 0
+	}
+	for k := range h.batt {
+		h.batt[k] = 0
+	}
+	for k := range h.rates {
+		h.rates[k] = 0
+	}
+}
+
+func process(name string, data *bufio.Scanner) {
+	nh := newRemoteHost()
 	for data.Scan() {
 		line := data.Text()
-		if line[0] == '#' {
+		if !strings.HasPrefix(line, _gotop) {
 			continue
 		}
-		if line[0:6] != _gotop {
-			continue
-		}
-		sub := line[6:]
+		sub := line[len(_gotop):]
 		switch {
 		case strings.HasPrefix(sub, _cpu): // int gotop_cpu_CPU0
-			procInt(host, line, sub[4:], _cpuData)
+			procInt(line, sub[len(_cpu):], nh.cpu)
 		case strings.HasPrefix(sub, _temp): // int gotop_temp_acpitz
-			procInt(host, line, sub[5:], _tempData)
+			procInt(line, sub[len(_temp):], nh.temp)
 		case strings.HasPrefix(sub, _net): // int gotop_net_recv
-			parts := strings.Split(sub[5:], " ")
-			if len(parts) < 2 {
-				log.Printf(`bad data; not enough columns in "%s"`, line)
-				continue
-			}
-			val, err := strconv.ParseFloat(parts[1], 64)
-			if err != nil {
-				log.Print(err)
-				continue
-			}
-			_netData[host+parts[0]] = val
+			procFloat(line, sub[len(_net):], nh.net)
 		case strings.HasPrefix(sub, _disk): // float % gotop_disk_:dev:mmcblk0p1
-			parts := strings.Split(sub[5:], " ")
-			if len(parts) < 2 {
-				log.Printf(`bad data; not enough columns in "%s"`, line)
-				continue
-			}
-			val, err := strconv.ParseFloat(parts[1], 64)
-			if err != nil {
-				log.Print(err)
-				continue
-			}
-			_diskData[host+parts[0]] = val
+			procFloat(line, sub[len(_disk):], nh.disk)
+		case strings.HasPrefix(sub, _batt): // float % gotop_battery_0
+			procFloat(line, sub[len(_batt):], nh.batt)
 		case strings.HasPrefix(sub, _mem): // float % gotop_memory_Main
-			parts := strings.Split(sub[7:], " ")
-			if len(parts) < 2 {
-				log.Printf(`bad data; not enough columns in "%s"`, line)
-				continue
-			}
-			val, err := strconv.ParseFloat(parts[1], 64)
-			if err != nil {
-				log.Print(err)
-				continue
-			}
-			_memData[host+parts[0]] = MemoryInfo{
-				Total:       100,
-				Used:        uint64(100.0 / val),
-				UsedPercent: val,
+			mems := make(map[string]float64)
+			procFloat(line, sub[len(_mem):], mems)
+			for k, val := range mems {
+				// Remotes only report percentages
+				nh.mem[k] = MemoryInfo{
+					Total:       100,
+					Used:        uint64(val),
+					UsedPercent: val,
+				}
 			}
 		default:
 			// NOP!  This is a metric we don't care about.
 		}
 	}
-	remoteLock.Unlock()
+	nh.polled = time.Now()
+
+	remoteLock.Lock()
+	defer remoteLock.Unlock()
+	if oh, ok := _hosts[name]; ok && !oh.polled.IsZero() {
+		secs := nh.polled.Sub(oh.polled).Seconds()
+		for k, v := range nh.net {
+			if prev, ok := oh.net[k]; ok && v >= prev && secs > 0 {
+				nh.rates[k] = (v - prev) / secs
+			}
+		}
+	}
+	_hosts[name] = nh
 }
 
-func procInt(host, line, sub string, data map[string]int) {
+func procInt(line, sub string, data map[string]int) {
+	fs := make(map[string]float64)
+	procFloat(line, sub, fs)
+	for k, v := range fs {
+		data[k] = int(v)
+	}
+}
+
+func procFloat(line, sub string, data map[string]float64) {
 	parts := strings.Split(sub, " ")
 	if len(parts) < 2 {
 		log.Printf(`bad data; not enough columns in "%s"`, line)
 		return
 	}
-	val, err := strconv.Atoi(parts[1])
+	val, err := strconv.ParseFloat(parts[1], 64)
 	if err != nil {
 		log.Print(err)
 		return
 	}
-	data[host+parts[0]] = val
+	data[parts[0]] = val
 }
 
-func updateTemp(temps map[string]int) map[string]error {
+// Local is the host name used for the machine gotop is running on.
+const Local = ""
+
+// RemoteNames returns the sorted names of the configured remotes.
+func RemoteNames() []string {
 	remoteLock.Lock()
-	for name, val := range _tempData {
-		temps[name] = val
+	defer remoteLock.Unlock()
+	rv := make([]string, 0, len(_hosts))
+	for name := range _hosts {
+		rv = append(rv, name)
 	}
-	remoteLock.Unlock()
-	return nil
+	sort.Strings(rv)
+	return rv
 }
 
-// FIXME The units are wrong: getting bytes, assuming they're %
-func updateMem(mems map[string]MemoryInfo) map[string]error {
+// HostCPU is UpdateCPU for a single host. Remote data isn't merged into the
+// local devices, so each host's CPUs are reported exclusively, and a remote's
+// aren't prefixed with its name.
+func HostCPU(host string, cpus map[string]int, interval time.Duration, logical bool) {
+	if host == Local {
+		UpdateCPU(cpus, interval, logical)
+		return
+	}
 	remoteLock.Lock()
-	for name, val := range _memData {
-		mems[name] = val
+	defer remoteLock.Unlock()
+	if h, ok := _hosts[host]; ok {
+		for name, val := range h.cpu {
+			cpus[name] = val
+		}
 	}
-	remoteLock.Unlock()
-	return nil
 }
 
-func updateUsage(cpus map[string]int, _ bool) map[string]error {
+// HostMem is UpdateMem for a single host. Remotes report only percentages;
+// the Total of remote memory is always 100.
+func HostMem(host string, mems map[string]MemoryInfo) {
+	if host == Local {
+		UpdateMem(mems)
+		return
+	}
 	remoteLock.Lock()
-	for name, val := range _cpuData {
-		cpus[name] = val
+	defer remoteLock.Unlock()
+	if h, ok := _hosts[host]; ok {
+		for name, val := range h.mem {
+			mems[name] = val
+		}
+	}
+}
+
+// HostTemps is UpdateTemps for a single host. Unlike the local sensors, all of
+// the remote's sensors are added to temps.
+func HostTemps(host string, temps map[string]int) {
+	if host == Local {
+		UpdateTemps(temps)
+		return
+	}
+	remoteLock.Lock()
+	defer remoteLock.Unlock()
+	if h, ok := _hosts[host]; ok {
+		for name, val := range h.temp {
+			temps[name] = val
+		}
 	}
-	remoteLock.Unlock()
-	return nil
+}
+
+// RemoteDisks returns the used fraction (0-1) of the remote's partitions,
+// keyed by device.
+func RemoteDisks(host string) map[string]float64 {
+	remoteLock.Lock()
+	defer remoteLock.Unlock()
+	rv := make(map[string]float64)
+	if h, ok := _hosts[host]; ok {
+		for name, val := range h.disk {
+			rv[strings.ReplaceAll(name, ":", "/")] = val
+		}
+	}
+	return rv
+}
+
+// RemoteNet returns the total bytes received and sent by the remote, as
+// counted by the remote since it started.
+func RemoteNet(host string) (recv, sent uint64) {
+	remoteLock.Lock()
+	defer remoteLock.Unlock()
+	if h, ok := _hosts[host]; ok {
+		return uint64(h.net["recv"]), uint64(h.net["sent"])
+	}
+	return 0, 0
+}
+
+// RemoteBatteries returns the remote's battery charge percentages, keyed by
+// battery index; "total" is the combined charge.
+func RemoteBatteries(host string) map[string]float64 {
+	remoteLock.Lock()
+	defer remoteLock.Unlock()
+	rv := make(map[string]float64)
+	if h, ok := _hosts[host]; ok {
+		for name, val := range h.batt {
+			rv[name] = val
+		}
+	}
+	return rv
 }
 
 func parseConfig(vars map[string]string) map[string]Remote {
@@ -242,7 +461,7 @@ func parseConfig(vars map[string]string) map[string]Remote {
 		if strings.HasPrefix(key, "remote-") {
 			parts := strings.Split(key, "-")
 			if len(parts) == 2 {
-				log.Printf("malformed Remote extension configuration '%s'; must be 'remote-NAME-url' or 'remote-NAME-refresh'", key)
+				log.Printf("malformed Remote extension configuration '%s'; must be 'remote-NAME-OPTION'", key)
 				continue
 			}
 			name := parts[1]
@@ -250,17 +469,26 @@ func parseConfig(vars map[string]string) map[string]Remote {
 			if !ok {
 				remote = Remote{}
 			}
-			if parts[2] == "url" {
+			switch parts[2] {
+			case "url":
 				remote.url = value
-			} else if parts[2] == "refresh" {
+			case "refresh":
 				sleep, err := strconv.Atoi(value)
 				if err != nil {
 					log.Printf("illegal Remote extension value for %s: '%s'.  Must be a duration in seconds, e.g. '2'", key, value)
 					continue
 				}
 				remote.refresh = time.Duration(sleep) * time.Second
-			} else {
-				log.Printf("bad configuration option for Remote extension: '%s'; must be 'remote-NAME-url' or 'remote-NAME-refresh'", key)
+			case "user":
+				remote.user = value
+			case "password":
+				remote.password = value
+			case "token":
+				remote.token = value
+			case "ca":
+				remote.ca = value
+			default:
+				log.Printf("bad configuration option for Remote extension: '%s'; must be one of 'remote-NAME-{url,refresh,user,password,token,ca}'", key)
 				continue
 			}
 			rv[name] = remote
@@ -276,4 +504,5 @@ const (
 	_net   = "net_"
 	_disk  = "disk_"
 	_mem   = "memory_"
+	_batt  = "battery_"
 )
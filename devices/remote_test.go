@@ -0,0 +1,118 @@
+package devices
+
+import (
+	"bufio"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemotePollTLS(t *testing.T) {
+	_hosts = make(map[string]*remoteHost)
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		bearer := r.Header.Get("Authorization") == "Bearer s3cret"
+		if !bearer && !(ok && user == "me" && pass == "pw") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintln(w, "# HELP nothing")
+		fmt.Fprintln(w, "gotop_cpu_CPU0 42")
+		fmt.Fprintln(w, "gotop_temp_acpitz 55")
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "gotop")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ca := filepath.Join(dir, "ca.pem")
+	err = ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0600)
+	assert.NoError(t, err)
+
+	rs := parseConfig(map[string]string{
+		"remote-box-url":      ts.URL,
+		"remote-box-ca":       ca,
+		"remote-box-token":    "s3cret",
+		"remote-bad-url":      ts.URL,
+		"remote-bad-ca":       ca,
+		"remote-bad-user":     "me",
+		"remote-bad-password": "nope",
+		"remote-anon-url":     ts.URL,
+	})
+	assert.Equal(t, 3, len(rs))
+
+	box := rs["box"]
+	assert.NoError(t, box.connect())
+	assert.NoError(t, box.poll("box"))
+	assert.Equal(t, 42, _hosts["box"].cpu["CPU0"])
+	assert.Equal(t, 55, _hosts["box"].temp["acpitz"])
+
+	bad := rs["bad"]
+	assert.NoError(t, bad.connect())
+	assert.Error(t, bad.poll("bad"))
+	bad.password = "pw"
+	assert.NoError(t, bad.poll("bad"))
+
+	// Without the CA, the self-signed certificate is rejected
+	anon := rs["anon"]
+	assert.NoError(t, anon.connect())
+	assert.Error(t, anon.poll("anon"))
+
+	missing := Remote{url: ts.URL, ca: filepath.Join(dir, "missing.pem")}
+	assert.Error(t, missing.connect())
+
+	// Basic authentication and a token can't both be sent
+	both := Remote{url: ts.URL, user: "me", password: "pw", token: "s3cret"}
+	assert.Error(t, both.connect())
+}
+
+func TestRemoteHosts(t *testing.T) {
+	_hosts = make(map[string]*remoteHost)
+	process("b", bufio.NewScanner(strings.NewReader("gotop_cpu_CPU0 10\ngotop_cpu_CPU1 30\ngotop_memory_Main 55.5\ngotop_temp_a 40\ngotop_temp_b 60\ngotop_net_recv 1000\ngotop_net_sent 10")))
+	failed("a", fmt.Errorf("no route to host"))
+	// Pretend the first poll was two seconds ago
+	_hosts["b"].polled = _hosts["b"].polled.Add(-2 * time.Second)
+	process("b", bufio.NewScanner(strings.NewReader("gotop_cpu_CPU0 10\ngotop_cpu_CPU1 30\ngotop_memory_Main 55.5\ngotop_temp_a 40\ngotop_temp_b 60\ngotop_net_recv 3000\ngotop_net_sent 10")))
+
+	hs := RemoteHosts()
+	if assert.Equal(t, 2, len(hs)) {
+		assert.Equal(t, "a", hs[0].Name)
+		assert.False(t, hs[0].Reachable)
+		b := hs[1]
+		assert.True(t, b.Reachable)
+		assert.Equal(t, 20.0, b.CPU)
+		assert.Equal(t, 55.5, b.Mem)
+		assert.Equal(t, 60, b.MaxTemp)
+		assert.InDelta(t, 1000.0, b.RecvRate, 5)
+		assert.Equal(t, 0.0, b.SentRate)
+	}
+
+	// An unreachable remote's last values aren't shown
+	failed("b", fmt.Errorf("connection refused"))
+	hs = RemoteHosts()
+	if assert.Equal(t, 2, len(hs)) {
+		b := hs[1]
+		assert.False(t, b.Reachable)
+		assert.Equal(t, 0.0, b.CPU)
+		assert.Equal(t, 0.0, b.Mem)
+		assert.Equal(t, 0, b.MaxTemp)
+		assert.Equal(t, 0.0, b.RecvRate)
+	}
+	cpus, temps := make(map[string]int), make(map[string]int)
+	HostCPU("b", cpus, time.Second, true)
+	HostTemps("b", temps)
+	assert.Equal(t, map[string]int{"CPU0": 0, "CPU1": 0}, cpus)
+	assert.Equal(t, map[string]int{"a": 0, "b": 0}, temps)
+	recv, _ := RemoteNet("b")
+	assert.Equal(t, uint64(3000), recv)
+}
@@ -0,0 +1,178 @@
+package devices
+
+import (
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/anatol/smart.go"
+)
+
+// ATA SMART attributes
+const (
+	ataPowerOnHours = 9
+	ataReallocated  = 5
+	ataPending      = 197
+	ataWearLeveling = 177
+	ataSSDLifeLeft  = 231
+	ataMediaWearout = 233
+	ataPrefailure   = smart.AtaAttributeFlagPrefailure
+)
+
+// smartUnsupported is the value of the SMART data a drive doesn't report
+const smartUnsupported = -1
+
+// SmartHealth is the health of a drive, from its SMART data. The counts and
+// percentages are -1 if the drive doesn't report them.
+type SmartHealth struct {
+	Name  string
+	Model string
+	// Type is nvme, sata, or scsi
+	Type string
+	// Err is set if the drive's SMART data couldn't be read, usually because
+	// gotop isn't running as root.
+	Err error
+	// Failing is true if the drive says it is: an NVMe critical warning, or
+	// a pre-failure ATA attribute at or below its threshold.
+	Failing      bool
+	PowerOnHours int64
+	// Reallocated and Pending are ATA sector counts
+	Reallocated int64
+	Pending     int64
+	// MediaErrors and PercentUsed are reported by NVMe drives
+	MediaErrors int64
+	PercentUsed int64
+	// Wear is the percentage of an ATA SSD's life that's used
+	Wear int64
+}
+
+// smartDrive is a drive opened for reading SMART data; dev is nil if it
+// couldn't be opened, in which case err says why.
+type smartDrive struct {
+	sync.Mutex
+	dev   smart.Device
+	model string
+	err   error
+}
+
+var _smartDrives = make(map[string]*smartDrive)
+
+// smartOpenError explains why smart.Open failed, which it doesn't say; usually
+// gotop isn't allowed to open the device.
+func smartOpenError(path string, err error) error {
+	f, oerr := os.OpenFile(path, os.O_RDWR, 0)
+	if oerr != nil {
+		return oerr
+	}
+	f.Close()
+	return err
+}
+
+// Smart reads the SMART data of the local drives, sorted by name. Drives that
+// couldn't be opened are included, with Err set.
+func Smart() []SmartHealth {
+	names := make([]string, 0, len(_smartDrives))
+	for name := range _smartDrives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rv := make([]SmartHealth, 0, len(names))
+	for _, name := range names {
+		d := _smartDrives[name]
+		if d.dev == nil {
+			h := unknownSmart(name, "")
+			h.Model, h.Err = d.model, d.err
+			rv = append(rv, h)
+			continue
+		}
+		d.Lock()
+		h := readSmart(name, d.dev)
+		d.Unlock()
+		h.Model = d.model
+		rv = append(rv, h)
+	}
+	return rv
+}
+
+// The SMART data that smart.go reads for each type of drive; devices that
+// don't have either only provide the generic attributes.
+type (
+	nvmeSmart interface {
+		ReadSMART() (*smart.NvmeSMARTLog, error)
+	}
+	ataSmart interface {
+		ReadSMARTData() (*smart.AtaSmartPage, error)
+		ReadSMARTThresholds() (*smart.AtaSmartThresholdsPage, error)
+	}
+)
+
+// unknownSmart is the health of a drive before anything is known about it.
+func unknownSmart(name, typ string) SmartHealth {
+	return SmartHealth{
+		Name:         name,
+		Type:         typ,
+		PowerOnHours: smartUnsupported,
+		Reallocated:  smartUnsupported,
+		Pending:      smartUnsupported,
+		MediaErrors:  smartUnsupported,
+		PercentUsed:  smartUnsupported,
+		Wear:         smartUnsupported,
+	}
+}
+
+func readSmart(name string, dev smart.Device) SmartHealth {
+	h := unknownSmart(name, dev.Type())
+	switch d := dev.(type) {
+	case nvmeSmart:
+		l, err := d.ReadSMART()
+		if err != nil {
+			h.Err = err
+			return h
+		}
+		h.Failing = l.CritWarning != 0
+		h.PowerOnHours = int64(l.PowerOnHours.Val[0])
+		h.MediaErrors = int64(l.MediaErrors.Val[0])
+		h.PercentUsed = int64(l.PercentUsed)
+	case ataSmart:
+		page, err := d.ReadSMARTData()
+		if err != nil {
+			h.Err = err
+			return h
+		}
+		for id, a := range page.Attrs {
+			switch id {
+			case ataPowerOnHours:
+				h.PowerOnHours = int64(a.ValueRaw & 0xffffffff)
+				if t, err := a.ParseAsDuration(); err == nil {
+					h.PowerOnHours = int64(t.Hours())
+				}
+			case ataReallocated:
+				h.Reallocated = int64(a.ValueRaw)
+			case ataPending:
+				h.Pending = int64(a.ValueRaw)
+			case ataWearLeveling, ataSSDLifeLeft, ataMediaWearout:
+				// the normalized value counts down from 100
+				if a.Current <= 100 {
+					h.Wear = int64(100 - a.Current)
+				}
+			}
+		}
+		// Drives without thresholds can't be judged
+		if th, err := d.ReadSMARTThresholds(); err == nil {
+			for id, t := range th.Thresholds {
+				a, ok := page.Attrs[id]
+				if ok && t > 0 && a.Flags&ataPrefailure != 0 && a.Current <= t {
+					h.Failing = true
+				}
+			}
+		}
+	default:
+		attrs, err := dev.ReadGenericAttributes()
+		if err != nil {
+			h.Err = err
+			return h
+		}
+		h.PowerOnHours = int64(attrs.PowerOnHours)
+	}
+	return h
+}
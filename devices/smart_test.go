@@ -0,0 +1,93 @@
+package devices
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anatol/smart.go"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDevice struct {
+	typ   string
+	attrs *smart.GenericAttributes
+	err   error
+}
+
+func (f fakeDevice) Type() string { return f.typ }
+func (f fakeDevice) Close() error { return nil }
+func (f fakeDevice) ReadGenericAttributes() (*smart.GenericAttributes, error) {
+	return f.attrs, f.err
+}
+
+type fakeNVMe struct {
+	fakeDevice
+	log smart.NvmeSMARTLog
+}
+
+func (f fakeNVMe) ReadSMART() (*smart.NvmeSMARTLog, error) { return &f.log, f.err }
+
+type fakeATA struct {
+	fakeDevice
+	page       smart.AtaSmartPage
+	thresholds smart.AtaSmartThresholdsPage
+}
+
+func (f fakeATA) ReadSMARTData() (*smart.AtaSmartPage, error) { return &f.page, f.err }
+func (f fakeATA) ReadSMARTThresholds() (*smart.AtaSmartThresholdsPage, error) {
+	return &f.thresholds, nil
+}
+
+func TestReadSmart(t *testing.T) {
+	hours := func(h int) uint64 { return uint64(time.Duration(h) * time.Hour / time.Minute) }
+	tests := []struct {
+		name string
+		dev  smart.Device
+		want SmartHealth
+	}{
+		{
+			name: "nvme",
+			dev: fakeNVMe{fakeDevice: fakeDevice{typ: "nvme"}, log: smart.NvmeSMARTLog{
+				PercentUsed:  7,
+				PowerOnHours: smart.Uint128{Val: [2]uint64{1234, 0}},
+				MediaErrors:  smart.Uint128{Val: [2]uint64{2, 0}},
+			}},
+			want: SmartHealth{Name: "nvme", Type: "nvme", PowerOnHours: 1234, Reallocated: -1, Pending: -1, MediaErrors: 2, PercentUsed: 7, Wear: -1},
+		},
+		{
+			name: "nvme critical",
+			dev:  fakeNVMe{fakeDevice: fakeDevice{typ: "nvme"}, log: smart.NvmeSMARTLog{CritWarning: 4}},
+			want: SmartHealth{Name: "nvme critical", Type: "nvme", Failing: true, PowerOnHours: 0, Reallocated: -1, Pending: -1, MediaErrors: 0, PercentUsed: 0, Wear: -1},
+		},
+		{
+			name: "sata",
+			dev: fakeATA{fakeDevice: fakeDevice{typ: "sata"}, page: smart.AtaSmartPage{Attrs: map[uint8]smart.AtaSmartAttr{
+				ataPowerOnHours: {Id: ataPowerOnHours, ValueRaw: hours(500), Type: smart.AtaDeviceAttributeTypeMin2Hour},
+				ataReallocated:  {Id: ataReallocated, Flags: ataPrefailure, Current: 100, ValueRaw: 8},
+				ataPending:      {Id: ataPending, ValueRaw: 1},
+				ataWearLeveling: {Id: ataWearLeveling, Current: 97},
+			}}, thresholds: smart.AtaSmartThresholdsPage{Thresholds: map[uint8]uint8{ataReallocated: 10}}},
+			want: SmartHealth{Name: "sata", Type: "sata", PowerOnHours: 500, Reallocated: 8, Pending: 1, MediaErrors: -1, PercentUsed: -1, Wear: 3},
+		},
+		{
+			name: "sata failing",
+			dev: fakeATA{fakeDevice: fakeDevice{typ: "sata"}, page: smart.AtaSmartPage{Attrs: map[uint8]smart.AtaSmartAttr{
+				ataReallocated: {Id: ataReallocated, Flags: ataPrefailure, Current: 5, ValueRaw: 4000},
+			}}, thresholds: smart.AtaSmartThresholdsPage{Thresholds: map[uint8]uint8{ataReallocated: 10}}},
+			want: SmartHealth{Name: "sata failing", Type: "sata", Failing: true, PowerOnHours: -1, Reallocated: 4000, Pending: -1, MediaErrors: -1, PercentUsed: -1, Wear: -1},
+		},
+		{
+			name: "scsi",
+			dev:  fakeDevice{typ: "scsi", attrs: &smart.GenericAttributes{PowerOnHours: 42}},
+			want: SmartHealth{Name: "scsi", Type: "scsi", PowerOnHours: 42, Reallocated: -1, Pending: -1, MediaErrors: -1, PercentUsed: -1, Wear: -1},
+		},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, readSmart(tc.name, tc.dev), tc.name)
+	}
+
+	err := errors.New("permission denied")
+	h := readSmart("broken", fakeNVMe{fakeDevice: fakeDevice{typ: "nvme", err: err}})
+	assert.Equal(t, err, h.Err)
+}
@@ -2,12 +2,16 @@ package devices
 
 import (
 	"log"
+	"sync"
 )
 
 // TODO add thermal history graph. Update when something changes?
 
 var tempUpdates []func(map[string]int) map[string]error
 
+var tempCriticals = make(map[string]int)
+var criticalLock sync.Mutex
+
 func RegisterTemp(update func(map[string]int) map[string]error) {
 	tempUpdates = append(tempUpdates, update)
 }
@@ -22,3 +26,23 @@ func UpdateTemps(temps map[string]int) {
 		}
 	}
 }
+
+// SetTempCritical records the temperature, in Celsius, at which a sensor is
+// critical. Temperature updaters call it for sensors that report one.
+func SetTempCritical(sensor string, celsius int) {
+	criticalLock.Lock()
+	defer criticalLock.Unlock()
+	tempCriticals[sensor] = celsius
+}
+
+// TempCriticals returns the critical temperatures, in Celsius, of the
+// sensors that report one.
+func TempCriticals() map[string]int {
+	criticalLock.Lock()
+	defer criticalLock.Unlock()
+	rv := make(map[string]int, len(tempCriticals))
+	for k, v := range tempCriticals {
+		rv[k] = v
+	}
+	return rv
+}
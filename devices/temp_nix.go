@@ -11,8 +11,6 @@ import (
 	"github.com/shirou/gopsutil/v3/host"
 )
 
-var smDevices map[string]smart.Device
-
 func init() {
 	devs() // Populate the sensorMap
 	RegisterStartup(startBlock)
@@ -22,7 +20,7 @@ func init() {
 }
 
 func startBlock(vars map[string]string) error {
-	smDevices = make(map[string]smart.Device)
+	_smartDrives = make(map[string]*smartDrive)
 
 	block, err := ghw.Block()
 	if err != nil {
@@ -33,16 +31,21 @@ func startBlock(vars map[string]string) error {
 		dev, err := smart.Open("/dev/" + disk.Name)
 		if err != nil {
 			log.Printf("error opening smart info for %s: %s", disk.Name, err)
+			err = smartOpenError("/dev/"+disk.Name, err)
+			_smartDrives[disk.Name] = &smartDrive{model: disk.Model, err: err}
 			continue
 		}
-		smDevices[disk.Name+"_"+disk.Model] = dev
+		_smartDrives[disk.Name] = &smartDrive{dev: dev, model: disk.Model}
 	}
 	return nil
 }
 
 func endBlock() error {
-	for name, dev := range smDevices {
-		err := dev.Close()
+	for name, d := range _smartDrives {
+		if d.dev == nil {
+			continue
+		}
+		err := d.dev.Close()
 		if err != nil {
 			log.Printf("error closing device %s: %s", name, err)
 		}
@@ -63,16 +66,24 @@ func getTemps(temps map[string]int) map[string]error {
 		label := sensorMap[sensor.SensorKey]
 		if _, ok := temps[label]; ok {
 			temps[label] = int(sensor.Temperature)
+			if sensor.Critical > 0 {
+				SetTempCritical(label, int(sensor.Critical))
+			}
 		}
 	}
 
-	for name, dev := range smDevices {
-		attr, err := dev.ReadGenericAttributes()
+	for name, d := range _smartDrives {
+		if d.dev == nil {
+			continue
+		}
+		d.Lock()
+		attr, err := d.dev.ReadGenericAttributes()
+		d.Unlock()
 		if err != nil {
 			log.Printf("error getting smart data for %s: %s", name, err)
 			continue
 		}
-		temps[name] = int(attr.Temperature)
+		temps[name+"_"+d.model] = int(attr.Temperature)
 	}
 	return nil
 }
@@ -20,6 +20,9 @@ func update(temps map[string]int) map[string]error {
 	for _, sensor := range sensors {
 		if _, ok := temps[sensor.SensorKey]; ok {
 			temps[sensor.SensorKey] = int(sensor.Temperature + 0.5)
+			if sensor.Critical > 0 {
+				SetTempCritical(sensor.SensorKey, int(sensor.Critical))
+			}
 		}
 	}
 	return nil
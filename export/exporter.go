@@ -0,0 +1,83 @@
+package export
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xxxserxxx/gotop/v4"
+)
+
+// Exporter sends samples to another monitoring system. Exporters are called
+// with all gotop metrics every update interval.
+type Exporter interface {
+	Export([]Sample) error
+}
+
+// Factory creates an Exporter from the configuration, or returns nil if the
+// exporter isn't enabled.
+type Factory func(gotop.Config) (Exporter, error)
+
+var factories = make(map[string]Factory)
+
+// RegisterExporter makes an exporter available; it's called by exporters'
+// init functions.
+func RegisterExporter(name string, f Factory) {
+	factories[name] = f
+}
+
+// Start creates the exporters enabled in the configuration and feeds them
+// samples until the program exits. Exporters that fail to start are returned
+// as errors and the others are still started.
+func Start(c gotop.Config) []error {
+	var errs []error
+	var exs []Exporter
+	var names []string
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ex, err := factories[name](c)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ex != nil {
+			exs = append(exs, ex)
+		}
+	}
+	if len(exs) == 0 {
+		return errs
+	}
+	go func() {
+		failing := make([]bool, len(exs))
+		for range time.NewTicker(c.UpdateInterval).C {
+			ss := Gather()
+			for i, ex := range exs {
+				err := ex.Export(ss)
+				// Only log the first of a run of failures, so that an
+				// unreachable endpoint doesn't fill up the log
+				if err != nil && !failing[i] {
+					log.Print(c.Tr.Value("error.exporter", err.Error()))
+				}
+				failing[i] = err != nil
+			}
+		}
+	}()
+	return errs
+}
+
+// split separates a gotop metric name into the domain and the key, e.g.
+// gotop_cpu_CPU0 becomes "cpu" and "CPU0". Other metrics have no domain.
+func split(name string) (domain, key string) {
+	if !strings.HasPrefix(name, "gotop_") {
+		return "", name
+	}
+	domain, key, ok := strings.Cut(strings.TrimPrefix(name, "gotop_"), "_")
+	if !ok {
+		return "", name
+	}
+	return domain, key
+}
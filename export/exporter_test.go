@@ -0,0 +1,137 @@
+package export
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xxxserxxx/gotop/v4"
+)
+
+var testSamples = []Sample{
+	{Name: "gotop_cpu_CPU0", Value: 12.5, Time: time.Unix(1600000000, 0)},
+	{Name: "gotop_cpu_CPU1", Value: 3, Time: time.Unix(1600000000, 0)},
+	{Name: "gotop_disk_:home", Value: 50, Time: time.Unix(1600000000, 0)},
+	{Name: "gotop_memory_Main memory", Value: 20, Time: time.Unix(1600000000, 0)},
+}
+
+// listen starts a UDP listener that collects the lines it receives.
+func listen(t *testing.T) (string, func(n int) []string) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn.LocalAddr().String(), func(n int) []string {
+		var lines []string
+		buf := make([]byte, 65536)
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		for len(lines) < n {
+			l, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				break
+			}
+			lines = append(lines, strings.Split(strings.TrimSuffix(string(buf[:l]), "\n"), "\n")...)
+		}
+		return lines
+	}
+}
+
+func TestInflux(t *testing.T) {
+	host, _ := os.Hostname()
+	want := []string{
+		"gotop_cpu,host=" + host + " CPU0=12.5,CPU1=3 1600000000000000000",
+		"gotop_disk,host=" + host + " :home=50 1600000000000000000",
+		"gotop_memory,host=" + host + ` Main\ memory=20 1600000000000000000`,
+	}
+
+	addr, read := listen(t)
+	var body string
+	var auth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, _ := ioutil.ReadAll(r.Body)
+		body, auth = string(bs), r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	file := filepath.Join(t.TempDir(), "gotop.influx")
+
+	tests := []struct {
+		url  string
+		read func() []string
+	}{
+		{"udp://" + addr, func() []string { return read(len(want)) }},
+		{ts.URL + "/api/v2/write?bucket=gotop", func() []string {
+			assert.Equal(t, "Token secret", auth)
+			return strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+		}},
+		{file, func() []string {
+			bs, err := ioutil.ReadFile(file)
+			assert.NoError(t, err)
+			return strings.Split(strings.TrimSuffix(string(bs), "\n"), "\n")
+		}},
+	}
+	for _, tc := range tests {
+		c := gotop.NewConfig()
+		c.InfluxURL, c.InfluxToken = tc.url, "secret"
+		ex, err := NewInflux(c)
+		if !assert.NoError(t, err, tc.url) {
+			continue
+		}
+		assert.NoError(t, ex.Export(testSamples), tc.url)
+		assert.Equal(t, want, tc.read(), tc.url)
+	}
+
+	c := gotop.NewConfig()
+	c.InfluxURL = "tcp://localhost:8089"
+	_, err := NewInflux(c)
+	assert.Error(t, err)
+}
+
+func TestStatsd(t *testing.T) {
+	addr, read := listen(t)
+	c := gotop.NewConfig()
+	c.StatsdAddr = addr
+	ex, err := NewStatsd(c)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, ex.Export(testSamples))
+	got := read(len(testSamples))
+	sort.Strings(got)
+	assert.Equal(t, []string{
+		"gotop.cpu.CPU0:12.5|g",
+		"gotop.cpu.CPU1:3|g",
+		"gotop.disk._home:50|g",
+		"gotop.memory.Main_memory:20|g",
+	}, got)
+
+	// Negative values would be read as decrements
+	assert.NoError(t, ex.Export([]Sample{
+		{Name: "gotop_temp_CPU", Value: -5, Time: time.Unix(1600000000, 0)},
+		{Name: "gotop_temp_GPU", Value: 0, Time: time.Unix(1600000000, 0)},
+	}))
+	assert.Equal(t, []string{
+		"gotop.temp.CPU:0|g",
+		"gotop.temp.CPU:-5|g",
+		"gotop.temp.GPU:0|g",
+	}, read(3))
+}
+
+func TestDisabledExporters(t *testing.T) {
+	c := gotop.NewConfig()
+	for name, f := range factories {
+		ex, err := f(c)
+		assert.NoError(t, err, name)
+		assert.Nil(t, ex, name)
+	}
+}
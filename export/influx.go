@@ -0,0 +1,192 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xxxserxxx/gotop/v4"
+)
+
+func init() {
+	RegisterExporter("influx", NewInflux)
+}
+
+// maxDatagram is the largest UDP payload sent; larger writes are split
+// between lines.
+const maxDatagram = 1400
+
+// Influx writes samples in the InfluxDB line protocol, one line per domain:
+//
+//	gotop_cpu,host=box CPU0=12.5,CPU1=3 1600000000000000000
+//
+// to a file, a UDP listener (such as Telegraf's socket_listener), or an HTTP
+// write endpoint.
+type Influx struct {
+	host   string
+	token  string
+	file   string
+	url    string
+	conn   net.Conn
+	client *http.Client
+}
+
+// NewInflux creates an Influx exporter if `influxurl` is configured. The URL
+// is either udp://HOST:PORT, an http(s) write URL, or a file path.
+func NewInflux(c gotop.Config) (Exporter, error) {
+	if c.InfluxURL == "" {
+		return nil, nil
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf(c.Tr.Value("error.nohostname", err.Error()))
+	}
+	in := &Influx{host: host, token: c.InfluxToken}
+	u, err := url.Parse(c.InfluxURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "udp":
+		if in.conn, err = net.Dial("udp", u.Host); err != nil {
+			return nil, err
+		}
+	case "http", "https":
+		in.url = c.InfluxURL
+		in.client = &http.Client{Timeout: 10 * time.Second}
+	case "file":
+		in.file = u.Path
+	case "":
+		in.file = c.InfluxURL
+	default:
+		return nil, fmt.Errorf(c.Tr.Value("error.influxurl", c.InfluxURL))
+	}
+	return in, nil
+}
+
+// Export writes the samples.
+func (in *Influx) Export(ss []Sample) error {
+	lines := in.lines(ss)
+	switch {
+	case in.conn != nil:
+		return writeDatagrams(in.conn, lines)
+	case in.url != "":
+		return in.post(lines)
+	default:
+		f, err := os.OpenFile(in.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, strings.Join(lines, ""))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+}
+
+func (in *Influx) post(lines []string) error {
+	req, err := http.NewRequest(http.MethodPost, in.url, strings.NewReader(strings.Join(lines, "")))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if in.token != "" {
+		req.Header.Set("Authorization", "Token "+in.token)
+	}
+	res, err := in.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// lines formats the samples, grouping gotop metrics into one line per domain.
+// Each line ends with a newline.
+func (in *Influx) lines(ss []Sample) []string {
+	type point struct {
+		measurement string
+		tags        string
+		fields      []string
+		ns          int64
+	}
+	var order []string
+	points := make(map[string]*point)
+	for _, s := range ss {
+		domain, key := split(s.Name)
+		measurement, field := "gotop_"+domain, key
+		if domain == "" {
+			measurement, field = s.Name, "value"
+		}
+		tags := ",host=" + escapeInflux(in.host, ",= ")
+		var lks []string
+		for k := range s.Labels {
+			lks = append(lks, k)
+		}
+		sort.Strings(lks)
+		for _, k := range lks {
+			tags += "," + escapeInflux(k, ",= ") + "=" + escapeInflux(s.Labels[k], ",= ")
+		}
+		id := measurement + tags
+		p, ok := points[id]
+		if !ok {
+			p = &point{measurement: measurement, tags: tags, ns: s.Time.UnixNano()}
+			points[id] = p
+			order = append(order, id)
+		}
+		p.fields = append(p.fields, escapeInflux(field, ",= ")+"="+strconv.FormatFloat(s.Value, 'g', -1, 64))
+	}
+	lines := make([]string, len(order))
+	for i, id := range order {
+		p := points[id]
+		lines[i] = fmt.Sprintf("%s%s %s %d\n", escapeInflux(p.measurement, ", "), p.tags, strings.Join(p.fields, ","), p.ns)
+	}
+	return lines
+}
+
+// escapeInflux backslash-escapes the special characters in a line protocol
+// element.
+func escapeInflux(s, special string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(special, r) || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// writeDatagrams sends newline-terminated lines, packing as many as fit into
+// each datagram.
+func writeDatagrams(conn net.Conn, lines []string) error {
+	var buf bytes.Buffer
+	for _, l := range lines {
+		if buf.Len() > 0 && buf.Len()+len(l) > maxDatagram {
+			if _, err := conn.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+		buf.WriteString(l)
+	}
+	if buf.Len() > 0 {
+		_, err := conn.Write(buf.Bytes())
+		return err
+	}
+	return nil
+}
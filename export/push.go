@@ -0,0 +1,202 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/xxxserxxx/lingo/v2"
+
+	"github.com/xxxserxxx/gotop/v4"
+)
+
+// Push formats
+const (
+	PushGateway     = "pushgateway"
+	PushRemoteWrite = "remotewrite"
+)
+
+const (
+	// maxPending bounds the number of samples kept for remote-write while the
+	// endpoint is unreachable; the oldest samples are dropped first.
+	maxPending = 100000
+	// maxBatch is the largest number of samples sent in one request.
+	maxBatch = 10000
+)
+
+// rejected is returned when the endpoint refuses the data, in which case
+// sending it again won't help.
+type rejected struct {
+	error
+}
+
+// Pusher periodically sends metrics to a Prometheus Pushgateway, or to a
+// Prometheus remote-write endpoint, for hosts that can't be scraped.
+//
+// A Pushgateway only keeps the latest value of each metric, so only the
+// current values are pushed. For remote-write, samples are taken every update
+// interval and sent in batches; batches that can't be delivered are kept and
+// sent with the next one.
+type Pusher struct {
+	url      string
+	format   string
+	job      string
+	instance string
+	interval time.Duration
+	sample   time.Duration
+	retries  int
+	backoff  time.Duration
+	client   *http.Client
+	tr       lingo.Translations
+	pending  []Sample
+}
+
+// NewPusher creates a Pusher from the push settings in the configuration.
+func NewPusher(c gotop.Config) (*Pusher, error) {
+	p := &Pusher{
+		url:      strings.TrimSuffix(c.PushURL, "/"),
+		format:   c.PushFormat,
+		job:      c.PushJob,
+		instance: c.PushInstance,
+		interval: c.PushInterval,
+		sample:   c.UpdateInterval,
+		retries:  3,
+		backoff:  time.Second,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		tr:       c.Tr,
+	}
+	switch p.format {
+	case "":
+		p.format = PushGateway
+	case PushGateway, PushRemoteWrite:
+	default:
+		return nil, fmt.Errorf(c.Tr.Value("error.pushformat", p.format))
+	}
+	if p.job == "" {
+		p.job = "gotop"
+	}
+	if p.instance == "" {
+		var err error
+		if p.instance, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf(c.Tr.Value("error.nohostname", err.Error()))
+		}
+	}
+	if p.interval <= 0 {
+		p.interval = 15 * time.Second
+	}
+	if p.sample <= 0 || p.sample > p.interval {
+		p.sample = p.interval
+	}
+	return p, nil
+}
+
+// Run pushes metrics until the program exits.
+func (p *Pusher) Run() {
+	sample := time.NewTicker(p.sample)
+	push := time.NewTicker(p.interval)
+	for {
+		select {
+		case <-sample.C:
+			if p.format == PushRemoteWrite {
+				p.add(Gather())
+			}
+		case <-push.C:
+			if err := p.Push(); err != nil {
+				log.Print(p.tr.Value("error.push", p.url, err.Error()))
+			}
+		}
+	}
+}
+
+// add queues samples for the next remote-write batch.
+func (p *Pusher) add(ss []Sample) {
+	p.pending = append(p.pending, ss...)
+	if len(p.pending) > maxPending {
+		p.pending = append([]Sample(nil), p.pending[len(p.pending)-maxPending:]...)
+	}
+}
+
+// Push sends the metrics now, retrying with an increasing delay if the
+// endpoint fails. Remote-write samples that couldn't be sent are kept for the
+// next push, unless the endpoint rejected them.
+func (p *Pusher) Push() error {
+	if p.format == PushRemoteWrite {
+		if len(p.pending) == 0 {
+			p.add(Gather())
+		}
+		for len(p.pending) > 0 {
+			n := len(p.pending)
+			if n > maxBatch {
+				n = maxBatch
+			}
+			err := p.send(http.MethodPost, p.url, encodeWriteRequest(p.pending[:n], p.job, p.instance))
+			var r rejected
+			if err != nil && !errors.As(err, &r) {
+				return err
+			}
+			p.pending = p.pending[n:]
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var buf bytes.Buffer
+	metrics.WritePrometheus(&buf, false)
+	u := fmt.Sprintf("%s/metrics/job/%s/instance/%s", p.url, url.PathEscape(p.job), url.PathEscape(p.instance))
+	return p.send(http.MethodPut, u, buf.Bytes())
+}
+
+func (p *Pusher) send(method, u string, body []byte) error {
+	var err error
+	delay := p.backoff
+	for try := 0; try <= p.retries; try++ {
+		if try > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		err = p.do(method, u, body)
+		var r rejected
+		if err == nil || errors.As(err, &r) {
+			return err
+		}
+	}
+	return err
+}
+
+func (p *Pusher) do(method, u string, body []byte) error {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if p.format == PushRemoteWrite {
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		req.Header.Set("Content-Encoding", "snappy")
+		req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	} else {
+		req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+	if res.StatusCode/100 != 2 {
+		err = fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(msg)))
+		if res.StatusCode/100 == 4 && res.StatusCode != http.StatusTooManyRequests {
+			return rejected{err}
+		}
+		return err
+	}
+	return nil
+}
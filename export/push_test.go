@@ -0,0 +1,192 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xxxserxxx/gotop/v4"
+)
+
+func init() {
+	metrics.NewGauge("gotop_test_push", func() float64 { return 42 })
+}
+
+// receiver stands in for a Pushgateway or remote-write endpoint. It fails
+// the first `fail` requests with the status.
+type receiver struct {
+	sync.Mutex
+	fail   int
+	status int
+	reqs   int
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+	r.reqs++
+	if r.reqs <= r.fail {
+		http.Error(w, "nope", r.status)
+		return
+	}
+	r.method, r.path, r.header = req.Method, req.URL.Path, req.Header
+	r.body, _ = ioutil.ReadAll(req.Body)
+}
+
+func newTestPusher(t *testing.T, url, format string) *Pusher {
+	c := gotop.NewConfig()
+	c.PushURL, c.PushFormat, c.PushInstance = url, format, "box"
+	p, err := NewPusher(c)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	p.backoff = time.Millisecond
+	return p
+}
+
+func TestPushGateway(t *testing.T) {
+	tests := []struct {
+		fail, status int
+		wantErr      bool
+		wantReqs     int
+	}{
+		{0, 0, false, 1},
+		{2, http.StatusInternalServerError, false, 3},
+		{10, http.StatusInternalServerError, true, 4},
+		{10, http.StatusBadRequest, true, 1},
+	}
+	for i, tc := range tests {
+		r := &receiver{fail: tc.fail, status: tc.status}
+		ts := httptest.NewServer(r)
+		p := newTestPusher(t, ts.URL+"/", "")
+		err := p.Push()
+		assert.Equal(t, tc.wantErr, err != nil, "case %d: %v", i, err)
+		assert.Equal(t, tc.wantReqs, r.reqs, "case %d", i)
+		if !tc.wantErr {
+			assert.Equal(t, http.MethodPut, r.method)
+			assert.Equal(t, "/metrics/job/gotop/instance/box", r.path)
+			assert.Contains(t, string(r.body), "gotop_test_push 42\n")
+		}
+		ts.Close()
+	}
+}
+
+func TestRemoteWrite(t *testing.T) {
+	r := &receiver{fail: 1, status: http.StatusServiceUnavailable}
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+	p := newTestPusher(t, ts.URL+"/api/v1/write", PushRemoteWrite)
+	p.retries = 0
+
+	now := time.Unix(1600000000, 0)
+	p.add([]Sample{{Name: "gotop_a", Value: 1, Time: now}, {Name: "gotop_b", Value: 2, Time: now}})
+	assert.Error(t, p.Push())
+	assert.Len(t, p.pending, 2, "failed samples are kept")
+	p.add([]Sample{{Name: "gotop_a", Value: 3, Time: now.Add(time.Second)}})
+	assert.NoError(t, p.Push())
+	assert.Len(t, p.pending, 0)
+
+	assert.Equal(t, "snappy", r.header.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", r.header.Get("Content-Type"))
+	got := decodeWriteRequest(t, snappyDecode(t, r.body))
+	want := map[string][]string{
+		"__name__=gotop_a,instance=box,job=gotop": {"1@1600000000000", "3@1600000001000"},
+		"__name__=gotop_b,instance=box,job=gotop": {"2@1600000000000"},
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestParse(t *testing.T) {
+	in := "gotop_cpu_CPU0 12.5\n\ngotop_x{a=\"1\",b=\"two\"} 3\n# comment\nbad\n"
+	now := time.Now()
+	ss := parse(bytes.NewBufferString(in), now)
+	assert.Equal(t, []Sample{
+		{Name: "gotop_cpu_CPU0", Value: 12.5, Time: now},
+		{Name: "gotop_x", Labels: map[string]string{"a": "1", "b": "two"}, Value: 3, Time: now},
+	}, ss)
+}
+
+// snappyDecode reads the literal-only snappy blocks that snappyEncode writes.
+func snappyDecode(t *testing.T, b []byte) []byte {
+	n, i := binary.Uvarint(b)
+	b = b[i:]
+	var out []byte
+	for len(b) > 0 {
+		var l int
+		switch tag := b[0] >> 2; {
+		case b[0]&3 != 0:
+			t.Fatalf("unexpected snappy element %x", b[0])
+		case tag < 60:
+			l, b = int(tag)+1, b[1:]
+		case tag == 60:
+			l, b = int(b[1])+1, b[2:]
+		case tag == 61:
+			l, b = int(b[1])|int(b[2])<<8+1, b[3:]
+		}
+		out, b = append(out, b[:l]...), b[l:]
+	}
+	assert.Equal(t, int(n), len(out))
+	return out
+}
+
+// decodeWriteRequest returns the samples of each time series, as value@ms.
+func decodeWriteRequest(t *testing.T, b []byte) map[string][]string {
+	rv := make(map[string][]string)
+	for _, ts := range fields(t, b)[1] {
+		f := fields(t, ts)
+		var labels []string
+		for _, l := range f[1] {
+			lf := fields(t, l)
+			labels = append(labels, string(lf[1][0])+"="+string(lf[2][0]))
+		}
+		key := strings.Join(labels, ",")
+		for _, s := range f[2] {
+			sf := fields(t, s)
+			v := math.Float64frombits(binary.LittleEndian.Uint64(sf[1][0]))
+			ms, _ := binary.Uvarint(sf[2][0])
+			rv[key] = append(rv[key], strconv.FormatFloat(v, 'g', -1, 64)+"@"+strconv.FormatUint(ms, 10))
+		}
+	}
+	return rv
+}
+
+// fields splits a protobuf message into the raw values of each field.
+func fields(t *testing.T, b []byte) map[int][][]byte {
+	rv := make(map[int][][]byte)
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		b = b[n:]
+		field := int(tag >> 3)
+		switch tag & 7 {
+		case 0:
+			_, n := binary.Uvarint(b)
+			rv[field] = append(rv[field], b[:n])
+			b = b[n:]
+		case 1:
+			rv[field] = append(rv[field], b[:8])
+			b = b[8:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			rv[field] = append(rv[field], b[n:n+int(l)])
+			b = b[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type %d", tag&7)
+		}
+	}
+	return rv
+}
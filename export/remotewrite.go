@@ -0,0 +1,114 @@
+package export
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// This file encodes Prometheus remote-write requests by hand, rather than
+// pulling in protobuf and snappy libraries for two small messages.
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label        { string name = 1; string value = 2; }
+//	message Sample       { double value = 1; int64 timestamp = 2; }
+
+// encodeWriteRequest builds a snappy-compressed WriteRequest holding the
+// samples, grouped into one time series per metric.
+func encodeWriteRequest(ss []Sample, job, instance string) []byte {
+	type series struct {
+		labels  [][2]string
+		samples []Sample
+	}
+	var order []string
+	bySeries := make(map[string]*series)
+	for _, s := range ss {
+		labels := [][2]string{{"__name__", s.Name}, {"instance", instance}, {"job", job}}
+		for k, v := range s.Labels {
+			labels = append(labels, [2]string{k, v})
+		}
+		sort.Slice(labels, func(i, j int) bool { return labels[i][0] < labels[j][0] })
+		key := ""
+		for _, l := range labels {
+			key += l[0] + "\x00" + l[1] + "\x00"
+		}
+		ts, ok := bySeries[key]
+		if !ok {
+			ts = &series{labels: labels}
+			bySeries[key] = ts
+			order = append(order, key)
+		}
+		ts.samples = append(ts.samples, s)
+	}
+
+	var req []byte
+	for _, key := range order {
+		ts := bySeries[key]
+		var msg []byte
+		for _, l := range ts.labels {
+			var label []byte
+			label = appendString(label, 1, l[0])
+			label = appendString(label, 2, l[1])
+			msg = appendBytes(msg, 1, label)
+		}
+		for _, s := range ts.samples {
+			var sample []byte
+			sample = appendTag(sample, 1, 1)
+			sample = appendFixed64(sample, math.Float64bits(s.Value))
+			sample = appendTag(sample, 2, 0)
+			sample = appendUvarint(sample, uint64(s.Time.UnixNano()/1e6))
+			msg = appendBytes(msg, 2, sample)
+		}
+		req = appendBytes(req, 1, msg)
+	}
+	return snappyEncode(req)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendFixed64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendTag(b []byte, field, wireType int) []byte {
+	return appendUvarint(b, uint64(field<<3|wireType))
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, 2)
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendString(b []byte, field int, v string) []byte {
+	return appendBytes(b, field, []byte(v))
+}
+
+// snappyEncode writes src in the snappy block format, using only literals.
+// This doesn't compress anything, but every snappy decoder can read it.
+func snappyEncode(src []byte) []byte {
+	dst := appendUvarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		n := len(src)
+		if n > 1<<16 {
+			n = 1 << 16
+		}
+		switch l := n - 1; {
+		case l < 60:
+			dst = append(dst, byte(l)<<2)
+		case l < 1<<8:
+			dst = append(dst, 60<<2, byte(l))
+		default:
+			dst = append(dst, 61<<2, byte(l), byte(l>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
@@ -0,0 +1,200 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/xxxserxxx/lingo/v2"
+
+	"github.com/xxxserxxx/gotop/v4"
+	"github.com/xxxserxxx/gotop/v4/logging"
+)
+
+func init() {
+	RegisterExporter("samples", NewSampleLog)
+}
+
+// Sample log formats
+const (
+	SampleLogCSV    = "csv"
+	SampleLogNDJSON = "ndjson"
+)
+
+// SAMPLEFILE is the base name of sample logs in the cache directory. The
+// extension is the format, and split logs add the domain, e.g. samples-cpu.csv
+const SAMPLEFILE = "samples"
+
+// SampleLog appends every sample to rotating files in the cache directory,
+// either one file for all metrics or one per domain.
+//
+// A CSV file's first column is `time`, in RFC 3339 format. In a wide file the
+// other columns are DOMAIN.DEVICE, e.g. cpu.CPU0, and in a domain's file they
+// are the device names; columns are sorted by name. The columns are fixed by
+// the first sample: devices that appear later are left out until gotop is
+// restarted, and devices that disappear have empty values.
+//
+// An NDJSON line has a `time` and, in a wide file, an object per domain
+// holding the values of its devices, e.g.
+//
+//	{"cpu":{"CPU0":12.5},"memory":{"Main":41.2},"time":"2021-01-01T10:00:00Z"}
+//
+// In a domain's file, the devices are at the top level.
+type SampleLog struct {
+	dir     string
+	format  string
+	split   bool
+	maxSize int64
+	tr      lingo.Translations
+	files   map[string]*sampleFile
+}
+
+type sampleFile struct {
+	w       *logging.RotateWriter
+	columns []string
+}
+
+// NewSampleLog creates a SampleLog if `samplelog` is configured.
+func NewSampleLog(c gotop.Config) (Exporter, error) {
+	switch c.SampleLog {
+	case "":
+		return nil, nil
+	case SampleLogCSV, SampleLogNDJSON:
+	default:
+		return nil, fmt.Errorf(c.Tr.Value("error.samplelog", c.SampleLog))
+	}
+	cache := c.ConfigDir.QueryCacheFolder()
+	if err := cache.MkdirAll(); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+	return &SampleLog{
+		dir:     cache.Path,
+		format:  c.SampleLog,
+		split:   c.SampleLogSplit,
+		maxSize: c.SampleLogSize,
+		tr:      c.Tr,
+		files:   make(map[string]*sampleFile),
+	}, nil
+}
+
+// Export appends the samples to the log files.
+func (l *SampleLog) Export(ss []Sample) error {
+	if len(ss) == 0 {
+		return nil
+	}
+	now := ss[0].Time.UTC().Format(time.RFC3339)
+	// CSV values by file, then by column
+	rows := make(map[string]map[string]float64)
+	// NDJSON objects by file
+	lines := make(map[string]map[string]interface{})
+	for _, s := range ss {
+		domain, key := split(s.Name)
+		file := ""
+		if l.split {
+			file = domain
+		}
+		if l.format == SampleLogCSV {
+			column := key
+			if !l.split && domain != "" {
+				column = domain + "." + key
+			}
+			if rows[file] == nil {
+				rows[file] = make(map[string]float64)
+			}
+			rows[file][column] = s.Value
+			continue
+		}
+		line := lines[file]
+		if line == nil {
+			line = map[string]interface{}{"time": now}
+			lines[file] = line
+		}
+		if l.split || domain == "" {
+			line[key] = s.Value
+			continue
+		}
+		d, ok := line[domain].(map[string]float64)
+		if !ok {
+			d = make(map[string]float64)
+			line[domain] = d
+		}
+		d[key] = s.Value
+	}
+	for file, vs := range rows {
+		if err := l.writeCSV(file, now, vs); err != nil {
+			return err
+		}
+	}
+	for file, line := range lines {
+		bs, err := json.Marshal(line)
+		if err != nil {
+			return err
+		}
+		f, err := l.file(file, nil)
+		if err != nil {
+			return err
+		}
+		if _, err = f.w.Write(append(bs, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *SampleLog) writeCSV(file, now string, vs map[string]float64) error {
+	f, err := l.file(file, vs)
+	if err != nil {
+		return err
+	}
+	row := make([]string, len(f.columns)+1)
+	row[0] = now
+	for i, c := range f.columns {
+		if v, ok := vs[c]; ok {
+			row[i+1] = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(row)
+	w.Flush()
+	_, err = f.w.Write(buf.Bytes())
+	return err
+}
+
+// file returns the log for a domain ("" for the wide log), opening it if
+// necessary. The columns of a new CSV log are taken from vs.
+func (l *SampleLog) file(domain string, vs map[string]float64) (*sampleFile, error) {
+	if f, ok := l.files[domain]; ok {
+		return f, nil
+	}
+	name := SAMPLEFILE
+	if domain != "" {
+		name += "-" + domain
+	}
+	name += "." + l.format
+	f := &sampleFile{}
+	var header []byte
+	if l.format == SampleLogCSV {
+		for c := range vs {
+			f.columns = append(f.columns, c)
+		}
+		sort.Strings(f.columns)
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(append([]string{"time"}, f.columns...))
+		w.Flush()
+		header = buf.Bytes()
+	}
+	var err error
+	if f.w, err = logging.NewRotateWriter(filepath.Join(l.dir, name), l.maxSize, header, l.tr); err != nil {
+		return nil, err
+	}
+	l.files[domain] = f
+	return f, nil
+}
@@ -0,0 +1,91 @@
+package export
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shibukawa/configdir"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xxxserxxx/gotop/v4"
+)
+
+func TestSampleLog(t *testing.T) {
+	later := []Sample{
+		{Name: "gotop_cpu_CPU0", Value: 20, Time: time.Unix(1600000001, 0)},
+		{Name: "gotop_cpu_CPU2", Value: 1, Time: time.Unix(1600000001, 0)},
+		{Name: "gotop_memory_Main memory", Value: 21, Time: time.Unix(1600000001, 0)},
+	}
+	tests := []struct {
+		format string
+		split  bool
+		want   map[string]string
+	}{
+		{SampleLogCSV, false, map[string]string{
+			"samples.csv": "time,cpu.CPU0,cpu.CPU1,disk.:home,memory.Main memory\n" +
+				"2020-09-13T12:26:40Z,12.5,3,50,20\n" +
+				"2020-09-13T12:26:41Z,20,,,21\n",
+		}},
+		{SampleLogCSV, true, map[string]string{
+			"samples-cpu.csv":    "time,CPU0,CPU1\n2020-09-13T12:26:40Z,12.5,3\n2020-09-13T12:26:41Z,20,\n",
+			"samples-disk.csv":   "time,:home\n2020-09-13T12:26:40Z,50\n",
+			"samples-memory.csv": "time,Main memory\n2020-09-13T12:26:40Z,20\n2020-09-13T12:26:41Z,21\n",
+		}},
+		{SampleLogNDJSON, false, map[string]string{
+			"samples.ndjson": `{"cpu":{"CPU0":12.5,"CPU1":3},"disk":{":home":50},"memory":{"Main memory":20},"time":"2020-09-13T12:26:40Z"}` + "\n" +
+				`{"cpu":{"CPU0":20,"CPU2":1},"memory":{"Main memory":21},"time":"2020-09-13T12:26:41Z"}` + "\n",
+		}},
+		{SampleLogNDJSON, true, map[string]string{
+			"samples-cpu.ndjson":    `{"CPU0":12.5,"CPU1":3,"time":"2020-09-13T12:26:40Z"}` + "\n" + `{"CPU0":20,"CPU2":1,"time":"2020-09-13T12:26:41Z"}` + "\n",
+			"samples-disk.ndjson":   `{":home":50,"time":"2020-09-13T12:26:40Z"}` + "\n",
+			"samples-memory.ndjson": `{"Main memory":20,"time":"2020-09-13T12:26:40Z"}` + "\n" + `{"Main memory":21,"time":"2020-09-13T12:26:41Z"}` + "\n",
+		}},
+	}
+	for _, tc := range tests {
+		c := gotop.NewConfig()
+		c.ConfigDir = configdir.New("", "gotoptest-samples")
+		path := c.ConfigDir.QueryCacheFolder().Path
+		os.RemoveAll(path)
+		c.SampleLog, c.SampleLogSplit = tc.format, tc.split
+		ex, err := NewSampleLog(c)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.NoError(t, ex.Export(testSamples))
+		assert.NoError(t, ex.Export(later))
+		for name, want := range tc.want {
+			bs, err := ioutil.ReadFile(filepath.Join(path, name))
+			assert.NoError(t, err)
+			assert.Equal(t, want, string(bs), "%s split=%t", tc.format, tc.split)
+		}
+		fs, _ := ioutil.ReadDir(path)
+		assert.Equal(t, len(tc.want), len(fs))
+		os.RemoveAll(path)
+	}
+}
+
+func TestSampleLogRotate(t *testing.T) {
+	c := gotop.NewConfig()
+	c.ConfigDir = configdir.New("", "gotoptest-samples")
+	path := c.ConfigDir.QueryCacheFolder().Path
+	os.RemoveAll(path)
+	defer os.RemoveAll(path)
+	c.SampleLog, c.SampleLogSize = SampleLogCSV, 100
+	ex, err := NewSampleLog(c)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, ex.Export(testSamples))
+	}
+	for _, name := range []string{"samples.csv", "samples.csv.0"} {
+		bs, err := ioutil.ReadFile(filepath.Join(path, name))
+		if assert.NoError(t, err) {
+			assert.True(t, strings.HasPrefix(string(bs), "time,cpu.CPU0,"), "%s starts with the header", name)
+		}
+	}
+}
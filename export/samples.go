@@ -0,0 +1,71 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// Sample is the value of one metric at a point in time.
+type Sample struct {
+	// Name is the Prometheus name of the metric, e.g. gotop_cpu_CPU0
+	Name string
+	// Labels holds any labels the metric was registered with
+	Labels map[string]string
+	Value  float64
+	Time   time.Time
+}
+
+// Gather returns the current value of every gotop metric. Widgets only
+// register metrics if some form of export is configured.
+func Gather() []Sample {
+	var buf bytes.Buffer
+	metrics.WritePrometheus(&buf, false)
+	return parse(&buf, time.Now())
+}
+
+// parse reads metrics in the Prometheus text format, as written by
+// metrics.WritePrometheus.
+func parse(buf *bytes.Buffer, now time.Time) []Sample {
+	var ss []Sample
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			continue
+		}
+		v, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			continue
+		}
+		s := Sample{Name: line[:i], Value: v, Time: now}
+		if j := strings.IndexByte(s.Name, '{'); j >= 0 && strings.HasSuffix(s.Name, "}") {
+			s.Labels = parseLabels(s.Name[j+1 : len(s.Name)-1])
+			s.Name = s.Name[:j]
+		}
+		ss = append(ss, s)
+	}
+	return ss
+}
+
+// parseLabels parses `a="x",b="y"`. Label values written by gotop never
+// contain quotes or commas.
+func parseLabels(l string) map[string]string {
+	ls := make(map[string]string)
+	for _, kv := range strings.Split(l, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		ls[strings.TrimSpace(k)] = strings.Trim(v, `"`)
+	}
+	return ls
+}
@@ -0,0 +1,74 @@
+// Package export makes gotop's metrics available to other programs.
+package export
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/xxxserxxx/gotop/v4"
+)
+
+// Handler returns the `/metrics` handler. If the configuration contains
+// credentials, requests must provide either the basic auth user & password or
+// the bearer token; other requests are rejected with a 401.
+func Handler(c gotop.Config) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, req *http.Request) {
+		metrics.WritePrometheus(w, true)
+	})
+	if c.ExportUser == "" && c.ExportToken == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if authorized(c, req) {
+			mux.ServeHTTP(w, req)
+			return
+		}
+		if c.ExportUser != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="gotop"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gotop"`)
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+func authorized(c gotop.Config, req *http.Request) bool {
+	if c.ExportToken != "" {
+		auth := req.Header.Get("Authorization")
+		if strings.HasPrefix(auth, "Bearer ") && equal(strings.TrimPrefix(auth, "Bearer "), c.ExportToken) {
+			return true
+		}
+	}
+	if c.ExportUser != "" {
+		user, pass, ok := req.BasicAuth()
+		// Both are always compared so that a wrong user takes as long as a wrong password
+		uok, pok := equal(user, c.ExportUser), equal(pass, c.ExportPassword)
+		if ok && uok && pok {
+			return true
+		}
+	}
+	return false
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// Serve exposes the metrics on the configured export port, blocking until the
+// server fails. The server uses TLS if both a certificate and a key are
+// configured.
+func Serve(c gotop.Config) error {
+	h := Handler(c)
+	if c.ExportTLSCert != "" || c.ExportTLSKey != "" {
+		if c.ExportTLSCert == "" || c.ExportTLSKey == "" {
+			return fmt.Errorf(c.Tr.Value("error.exporttls"))
+		}
+		return http.ListenAndServeTLS(c.ExportPort, c.ExportTLSCert, c.ExportTLSKey, h)
+	}
+	return http.ListenAndServe(c.ExportPort, h)
+}
@@ -0,0 +1,42 @@
+package export
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xxxserxxx/gotop/v4"
+)
+
+func TestHandlerAuth(t *testing.T) {
+	tests := []struct {
+		user, pass, token string
+		req               func(r *http.Request)
+		want              int
+	}{
+		{"", "", "", func(r *http.Request) {}, http.StatusOK},
+		{"me", "pw", "", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"me", "pw", "", func(r *http.Request) { r.SetBasicAuth("me", "pw") }, http.StatusOK},
+		{"me", "pw", "", func(r *http.Request) { r.SetBasicAuth("me", "bad") }, http.StatusUnauthorized},
+		{"", "", "tok", func(r *http.Request) { r.Header.Set("Authorization", "Bearer tok") }, http.StatusOK},
+		{"", "", "tok", func(r *http.Request) { r.Header.Set("Authorization", "Bearer bad") }, http.StatusUnauthorized},
+		{"me", "pw", "tok", func(r *http.Request) { r.Header.Set("Authorization", "Bearer tok") }, http.StatusOK},
+		{"me", "pw", "tok", func(r *http.Request) { r.SetBasicAuth("me", "pw") }, http.StatusOK},
+	}
+	for i, tc := range tests {
+		c := gotop.NewConfig()
+		c.ExportUser, c.ExportPassword, c.ExportToken = tc.user, tc.pass, tc.token
+		ts := httptest.NewTLSServer(Handler(c))
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/metrics", nil)
+		assert.NoError(t, err)
+		tc.req(req)
+		res, err := ts.Client().Do(req)
+		if assert.NoError(t, err) {
+			assert.Equal(t, tc.want, res.StatusCode, "case %d", i)
+			res.Body.Close()
+		}
+		ts.Close()
+	}
+}
@@ -0,0 +1,66 @@
+package export
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/xxxserxxx/gotop/v4"
+)
+
+func init() {
+	RegisterExporter("statsd", NewStatsd)
+}
+
+// Statsd sends samples as StatsD gauges over UDP, named
+// PREFIX.DOMAIN.KEY, e.g. gotop.cpu.CPU0:12.5|g
+type Statsd struct {
+	prefix string
+	conn   net.Conn
+}
+
+// NewStatsd creates a StatsD exporter if `statsdaddr` is configured.
+func NewStatsd(c gotop.Config) (Exporter, error) {
+	if c.StatsdAddr == "" {
+		return nil, nil
+	}
+	conn, err := net.Dial("udp", c.StatsdAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &Statsd{prefix: c.StatsdPrefix, conn: conn}, nil
+}
+
+// Export sends the samples.
+func (s *Statsd) Export(ss []Sample) error {
+	lines := make([]string, len(ss))
+	for i, sm := range ss {
+		name := sm.Name
+		if domain, key := split(sm.Name); domain != "" {
+			name = domain + "." + key
+		}
+		if s.prefix != "" {
+			name = s.prefix + "." + name
+		}
+		name = statsdName(name)
+		lines[i] = name + ":" + strconv.FormatFloat(sm.Value, 'f', -1, 64) + "|g\n"
+		// A signed gauge value is a change to the gauge, so negative
+		// values are set by zeroing it first. Both go in the same datagram
+		// to keep them in order.
+		if sm.Value < 0 {
+			lines[i] = name + ":0|g\n" + lines[i]
+		}
+	}
+	return writeDatagrams(s.conn, lines)
+}
+
+// statsdName replaces the characters that are special to StatsD.
+func statsdName(n string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', ' ', '\n':
+			return '_'
+		}
+		return r
+	}, n)
+}
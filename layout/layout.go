@@ -1,6 +1,7 @@
 package layout
 
 import (
+	"image"
 	"log"
 	"sort"
 	"strings"
@@ -8,12 +9,22 @@ import (
 	"github.com/xxxserxxx/lingo/v2"
 
 	"github.com/xxxserxxx/gotop/v4"
+	"github.com/xxxserxxx/gotop/v4/devices"
+	"github.com/xxxserxxx/gotop/v4/termui"
+	"github.com/xxxserxxx/gotop/v4/utils"
 	"github.com/xxxserxxx/gotop/v4/widgets"
 
 	ui "github.com/gizak/termui/v3"
 )
 
 type layout struct {
+	Pages []page
+}
+
+// page is one screen of a layout.
+type page struct {
+	// Name is empty for the first page if the layout doesn't name it
+	Name string
 	Rows [][]widgetRule
 }
 
@@ -21,29 +32,66 @@ type widgetRule struct {
 	Widget string
 	Weight float64
 	Height int
+	// Options override the configuration for the widget; flags have empty
+	// values
+	Options map[string]string
 }
 
+// MyGrid is the UI of a layout. Only the current page is displayed, but the
+// widgets of every page keep updating.
 type MyGrid struct {
 	*ui.Grid
+	// Lines holds the graphs of every page
 	Lines []widgets.Scalable
-	Proc  *widgets.ProcWidget
-	Net   *widgets.NetWidget
+	// Stateful holds every widget whose data can be shared by a daemon, in
+	// layout order.
+	Stateful []widgets.Stateful
+	// Samplers holds every widget that alert rules apply to.
+	Samplers []widgets.Sampler
+	// Critical holds every widget that reports critical conditions.
+	Critical []widgets.Critical
+	pages    []*gridPage
+	page     int
+}
+
+// gridPage is the UI of a page of the layout.
+type gridPage struct {
+	name string
+	grid *ui.Grid
+	// focus is the index of the focused widget in grid.Items
+	focus int
+	// zoomed is true if the focused widget fills the page
+	zoomed bool
 }
 
-var widgetNames []string = []string{"cpu", "disk", "mem", "temp", "net", "procs", "batt"}
+var widgetNames []string = []string{"cpu", "disk", "diskio", "smart", "mem", "temp", "net", "netif", "conns", "ports", "procs", "batt", "hosts"}
 var tr lingo.Translations
 
-func Layout(wl layout, c gotop.Config) (*MyGrid, error) {
+// Layout builds the widgets for the layout, displaying data from host, which
+// is either the name of a remote or devices.Local. Metrics are only exported
+// for the local host.
+func Layout(wl layout, c gotop.Config, host string) (*MyGrid, error) {
 	tr = c.Tr
-	rowDefs := wl.Rows
+	grid := &MyGrid{}
+	for _, p := range wl.Pages {
+		grid.pages = append(grid.pages, grid.layoutPage(p, c, host))
+	}
+	grid.SetPage(0)
+	return grid, nil
+}
+
+// layoutPage builds the widgets of a page, and adds them to the grid's lists
+// of widgets.
+func (grid *MyGrid) layoutPage(p page, c gotop.Config, host string) *gridPage {
+	rowDefs := p.Rows
 	uiRows := make([][]interface{}, 0)
-	numRows := countNumRows(wl.Rows)
+	numRows := countNumRows(p.Rows)
 	var uiRow []interface{}
 	maxHeight := 0
 	heights := make([]int, 0)
 	var h int
 	for len(rowDefs) > 0 {
-		h, uiRow, rowDefs = processRow(c, numRows, rowDefs)
+		h, uiRow, rowDefs = processRow(c, host, numRows, rowDefs)
 		maxHeight += h
 		uiRows = append(uiRows, uiRow)
 		heights = append(heights, h)
@@ -53,26 +101,182 @@ func Layout(wl layout, c gotop.Config) (*MyGrid, error) {
 		rh := float64(heights[i]) / float64(maxHeight)
 		rgs = append(rgs, ui.NewRow(rh, ur...))
 	}
-	grid := &MyGrid{ui.NewGrid(), nil, nil, nil}
-	grid.Set(rgs...)
-	grid.Lines = deepFindScalable(rgs)
-	res := deepFindWidget(uiRows, func(gs interface{}) interface{} {
-		p, ok := gs.(*widgets.ProcWidget)
-		if ok {
-			return p
+	gp := &gridPage{name: p.Name, grid: ui.NewGrid()}
+	gp.grid.Set(rgs...)
+	// Navigation keys have always gone to the process list, so it starts out
+	// focused
+	for i, item := range gp.grid.Items {
+		if _, ok := item.Entry.(*widgets.ProcWidget); ok {
+			gp.focus = i
+			break
 		}
-		return nil
-	})
-	grid.Proc, _ = res.(*widgets.ProcWidget)
-	res = deepFindWidget(uiRows, func(gs interface{}) interface{} {
-		p, ok := gs.(*widgets.NetWidget)
-		if ok {
-			return p
+	}
+	grid.Lines = append(grid.Lines, deepFindScalable(rgs)...)
+	for _, w := range deepFindAll(rgs) {
+		if s, ok := w.(widgets.Stateful); ok {
+			grid.Stateful = append(grid.Stateful, s)
+		}
+		if s, ok := w.(widgets.Sampler); ok {
+			grid.Samplers = append(grid.Samplers, s)
 		}
+		if c, ok := w.(widgets.Critical); ok {
+			grid.Critical = append(grid.Critical, c)
+		}
+	}
+	return gp
+}
+
+// SetRect sizes every page.
+func (grid *MyGrid) SetRect(x1, y1, x2, y2 int) {
+	for _, p := range grid.pages {
+		p.grid.SetRect(x1, y1, x2, y2)
+	}
+}
+
+// Pages returns the names of the pages; unnamed pages have empty names.
+func (grid *MyGrid) Pages() []string {
+	names := make([]string, len(grid.pages))
+	for i, p := range grid.pages {
+		names[i] = p.name
+	}
+	return names
+}
+
+// Page returns the index of the page being displayed.
+func (grid *MyGrid) Page() int {
+	return grid.page
+}
+
+// SetPage displays page i, returning false if there's no such page.
+func (grid *MyGrid) SetPage(i int) bool {
+	if i < 0 || i >= len(grid.pages) {
+		return false
+	}
+	p := grid.pages[i]
+	grid.page = i
+	grid.Grid = p.grid
+	return true
+}
+
+// Focused returns the focused widget of the current page, or nil if the page
+// is empty.
+func (grid *MyGrid) Focused() ui.Drawable {
+	p := grid.pages[grid.page]
+	if p.focus >= len(p.grid.Items) {
 		return nil
-	})
-	grid.Net, _ = res.(*widgets.NetWidget)
-	return grid, nil
+	}
+	d, _ := p.grid.Items[p.focus].Entry.(ui.Drawable)
+	return d
+}
+
+// FocusNext focuses the next widget of the current page, in layout order.
+func (grid *MyGrid) FocusNext() {
+	p := grid.pages[grid.page]
+	if len(p.grid.Items) > 0 {
+		p.focus = (p.focus + 1) % len(p.grid.Items)
+	}
+}
+
+// FocusAt focuses the widget at the point, returning false if there's none.
+func (grid *MyGrid) FocusAt(x, y int) bool {
+	p := grid.pages[grid.page]
+	if p.zoomed {
+		return image.Pt(x, y).In(grid.Rectangle)
+	}
+	for i, item := range p.grid.Items {
+		if d, ok := item.Entry.(ui.Drawable); ok && image.Pt(x, y).In(d.GetRect()) {
+			p.focus = i
+			return true
+		}
+	}
+	return false
+}
+
+// HandleEvent offers the event to the interactive widgets of the current page,
+// returning true if the page needs to be drawn. Mouse events go to the widget
+// under the pointer, which is focused. Other events go to the focused
+// widget, or else to the first of the other widgets that handles them, which
+// is then focused. Only the focused widget gets events while it's zoomed.
+func (grid *MyGrid) HandleEvent(e ui.Event) bool {
+	p := grid.pages[grid.page]
+	if m, ok := e.Payload.(ui.Mouse); ok {
+		prev := p.focus
+		if !grid.FocusAt(m.X, m.Y) {
+			return false
+		}
+		if !handle(grid.Focused(), e) && e.ID == "<MouseLeft>" {
+			return p.focus != prev
+		}
+		return true
+	}
+	if handle(grid.Focused(), e) {
+		return true
+	}
+	if p.zoomed {
+		return false
+	}
+	for i, item := range p.grid.Items {
+		if i != p.focus && handle(item.Entry, e) {
+			p.focus = i
+			return true
+		}
+	}
+	return false
+}
+
+// handle offers the event to the widget, if it's interactive.
+func handle(w interface{}, e ui.Event) bool {
+	iw, ok := w.(widgets.Interactive)
+	if !ok {
+		return false
+	}
+	iw.Lock()
+	defer iw.Unlock()
+	return iw.HandleEvent(e)
+}
+
+// ToggleZoom switches between showing only the focused widget, filling the
+// page, and showing the whole page.
+func (grid *MyGrid) ToggleZoom() {
+	p := grid.pages[grid.page]
+	p.zoomed = !p.zoomed && grid.Focused() != nil
+}
+
+// Draw draws the current page, or its focused widget if it's zoomed, and
+// highlights the border of the focused widget.
+func (grid *MyGrid) Draw(buf *ui.Buffer) {
+	f := grid.Focused()
+	if grid.pages[grid.page].zoomed && f != nil {
+		f.SetRect(grid.Min.X, grid.Min.Y, grid.Max.X, grid.Max.Y)
+		f.Lock()
+		f.Draw(buf)
+		f.Unlock()
+	} else {
+		grid.Grid.Draw(buf)
+	}
+	if f != nil && len(grid.pages[grid.page].grid.Items) > 1 {
+		highlight(buf, f.GetRect())
+	}
+}
+
+// highlight restyles the border of the rectangle, keeping its runes so titles
+// are still shown.
+func highlight(buf *ui.Buffer, r image.Rectangle) {
+	style := ui.NewStyle(widgets.FocusColor, ui.Theme.Block.Border.Bg, ui.ModifierBold)
+	restyle := func(x, y int) {
+		p := image.Pt(x, y)
+		c := buf.GetCell(p)
+		c.Style = style
+		buf.SetCell(c, p)
+	}
+	for x := r.Min.X; x < r.Max.X; x++ {
+		restyle(x, r.Min.Y)
+		restyle(x, r.Max.Y-1)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		restyle(r.Min.X, y)
+		restyle(r.Max.X-1, y)
+	}
 }
 
 // processRow eats a single row from the input list of rows and returns a UI
@@ -83,8 +287,8 @@ func Layout(wl layout, c gotop.Config) (*MyGrid, error) {
 // if there's a row span widget in the row; in this case, it'll consume as many
 // rows as the largest row span object in the row, and produce an uber-row
 // containing all that stuff. It returns a slice without the consumed elements.
-func processRow(c gotop.Config, numRows int, rowDefs [][]widgetRule) (int, []interface{}, [][]widgetRule) {
-	// Recursive function #3.  See the comment in deepFindProc.
+func processRow(c gotop.Config, host string, numRows int, rowDefs [][]widgetRule) (int, []interface{}, [][]widgetRule) {
+	// Recursive function #3.  See the comment in deepFindScalable.
 	if len(rowDefs) < 1 {
 		return 0, nil, [][]widgetRule{}
 	}
@@ -134,7 +338,7 @@ outer:
 			for k := w; k < len(colHeights); k++ { // there are enough columns
 				ch := colHeights[k]
 				if ch+widg.Height <= maxHeight {
-					widget := makeWidget(c, widg)
+					widget := makeWidget(c, host, widg)
 					columns[k] = append(columns[k], ui.NewRow(float64(widg.Height)/float64(maxHeight), widget))
 					colHeights[k] += widg.Height
 					placed = true
@@ -162,55 +366,184 @@ type Metric interface {
 	EnableMetric()
 }
 
-func makeWidget(c gotop.Config, widRule widgetRule) interface{} {
+func makeWidget(c gotop.Config, host string, widRule widgetRule) interface{} {
 	var w Metric
+	for k := range widRule.Options {
+		if _, ok := widgetOptions[widRule.Widget][k]; !ok {
+			log.Printf(tr.Value("layout.error.option", k, widRule.Widget))
+		}
+	}
 	switch widRule.Widget {
 	case "disk":
-		dw := widgets.NewDiskWidget()
+		var mounts []string
+		if m, ok := widRule.Options["mounts"]; ok {
+			for _, p := range strings.Split(m, ",") {
+				mounts = append(mounts, strings.TrimSpace(p))
+			}
+		}
+		dw := widgets.NewDiskWidget(mounts, host)
+		dw.Warn = c.DiskWarn
+		dw.WarnColor = ui.Color(c.Colorscheme.TempHigh)
+		columns := c.DiskColumns
+		if cs, ok := widRule.Options["columns"]; ok {
+			columns = strings.Split(cs, ",")
+		}
+		dw.SetColumns(columns)
 		w = dw
+	case "smart":
+		sw := widgets.NewSmartWidget(host)
+		sw.CursorColor = ui.Color(c.Colorscheme.ProcCursor)
+		sw.WarnColor = ui.Color(c.Colorscheme.TempHigh)
+		w = sw
+	case "diskio":
+		dio := widgets.NewDiskIOWidget(host)
+		dio.Colors = []ui.Color{ui.Color(c.Colorscheme.Sparklines[0]), ui.Color(c.Colorscheme.Sparklines[1])}
+		dio.TitleColor = ui.Color(c.Colorscheme.BorderLabel)
+		setSparklineOptions(c, widRule, dio.SparklineGroup, dio.MaxLabel)
+		w = dio
 	case "cpu":
-		cpu := widgets.NewCPUWidget(c.UpdateInterval, c.GraphHorizontalScale, c.AverageLoad, c.PercpuLoad)
+		avg, percpu := c.AverageLoad, c.PercpuLoad
+		if widRule.hasOption("avg") || widRule.hasOption("percpu") {
+			avg, percpu = widRule.hasOption("avg"), widRule.hasOption("percpu")
+		}
+		cpu := widgets.NewCPUWidget(c.UpdateInterval, c.GraphHorizontalScale, avg, percpu, host)
 		assignColors(cpu.Data, c.Colorscheme.CPULines, cpu.LineColors)
 		w = cpu
 	case "mem":
-		m := widgets.NewMemWidget(c.UpdateInterval, c.GraphHorizontalScale)
+		m := widgets.NewMemWidget(c.UpdateInterval, c.GraphHorizontalScale, host)
 		assignColors(m.Data, c.Colorscheme.MemLines, m.LineColors)
 		w = m
 	case "batt":
-		b := widgets.NewBatteryWidget(c.GraphHorizontalScale)
+		b := widgets.NewBatteryWidget(c.GraphHorizontalScale, host)
 		assignColors(b.Data, c.Colorscheme.BattLines, b.LineColors)
 		w = b
 	case "temp":
-		t := widgets.NewTempWidget(c.TempScale, c.Temps)
+		temps := c.Temps
+		if ss, ok := widRule.Options["sensors"]; ok {
+			temps = nil
+			for _, p := range strings.Split(ss, ",") {
+				temps = append(temps, strings.TrimSpace(p))
+			}
+			// Only the local sensors are known before the widget is updated
+			if host == devices.Local {
+				for _, p := range unmatchedSensors(temps, devices.Devices(devices.Temperatures, true)) {
+					log.Printf(tr.Value("layout.error.sensors", p))
+				}
+			}
+		}
+		t := widgets.NewTempWidget(c.TempScale, temps, host)
 		t.TempLowColor = ui.Color(c.Colorscheme.TempLow)
 		t.TempHighColor = ui.Color(c.Colorscheme.TempHigh)
 		w = t
 	case "net":
-		n := widgets.NewNetWidget(c.NetInterface)
+		iface, ok := widRule.Options["iface"]
+		if !ok {
+			iface = c.NetInterface
+		}
+		n := widgets.NewNetWidget(iface, host)
+		if ok {
+			n.Name = iface
+		}
 		n.Lines[0].LineColor = ui.Color(c.Colorscheme.Sparklines[0])
 		n.Lines[0].TitleColor = ui.Color(c.Colorscheme.BorderLabel)
 		n.Lines[1].LineColor = ui.Color(c.Colorscheme.Sparklines[1])
 		n.Lines[1].TitleColor = ui.Color(c.Colorscheme.BorderLabel)
-		n.Mbps = c.Mbps
+		n.Units = c.Units
+		if widRule.hasOption("bits") || widRule.hasOption("mbps") {
+			n.Units.Bits = true
+		} else if widRule.hasOption("bytes") {
+			n.Units.Bits = false
+		}
+		n.Packets = c.NetPackets || widRule.hasOption("packets")
+		setSparklineOptions(c, widRule, n.SparklineGroup, n.MaxLabel)
+		w = n
+	case "netif":
+		iface, ok := widRule.Options["iface"]
+		if !ok {
+			iface = c.NetInterface
+		}
+		n := widgets.NewNetIfWidget(iface, host)
+		n.RecvColor = ui.Color(c.Colorscheme.Sparklines[0])
+		n.SentColor = ui.Color(c.Colorscheme.Sparklines[1])
+		n.TitleColor = ui.Color(c.Colorscheme.BorderLabel)
+		setSparklineOptions(c, widRule, n.SparklineGroup, n.MaxLabel)
 		w = n
+	case "conns":
+		cw := widgets.NewConnsWidget(host)
+		cw.CursorColor = ui.Color(c.Colorscheme.ProcCursor)
+		w = cw
+	case "ports":
+		pw := widgets.NewPortsWidget(host)
+		pw.CursorColor = ui.Color(c.Colorscheme.ProcCursor)
+		pw.NewColor = ui.Color(c.Colorscheme.TempHigh)
+		w = pw
 	case "procs":
-		p := widgets.NewProcWidget()
+		p := widgets.NewProcWidget(host)
 		p.CursorColor = ui.Color(c.Colorscheme.ProcCursor)
 		w = p
+	case "hosts":
+		h := widgets.NewHostsWidget(c.TempScale)
+		h.CursorColor = ui.Color(c.Colorscheme.ProcCursor)
+		w = h
 	case "power":
-		b := widgets.NewBatteryGauge()
+		b := widgets.NewBatteryGauge(host)
 		b.BarColor = ui.Color(c.Colorscheme.ProcCursor)
 		w = b
 	default:
 		log.Printf(tr.Value("layout.error.widget", widRule.Widget, strings.Join(widgetNames, ",")))
 		return ui.NewBlock()
 	}
-	if c.ExportPort != "" {
+	if c.MetricsEnabled() && host == devices.Local {
 		w.EnableMetric()
 	}
 	return w
 }
 
+// setSparklineOptions applies the sparkline options of the config and of the
+// rule to g; label formats the values of the legend.
+func setSparklineOptions(c gotop.Config, widRule widgetRule, g *termui.SparklineGroup, label func(int) string) {
+	g.Log = c.SparklineLog || widRule.hasOption("log")
+	g.Shared = c.SparklineShared || widRule.hasOption("shared")
+	if c.SparklineLegend || widRule.hasOption("legend") {
+		g.MaxLabel = label
+	}
+	if m, ok := widRule.Options["max"]; ok {
+		max, err := utils.ParseBytes(m)
+		if err != nil {
+			log.Printf(tr.Value("layout.error.max", m, widRule.Widget, err.Error()))
+			return
+		}
+		g.Max = int(max)
+	}
+}
+
+func (w widgetRule) hasOption(name string) bool {
+	_, ok := w.Options[name]
+	return ok
+}
+
+// unmatchedSensors returns the patterns, other than ! exclusions, that match
+// none of the sensors; they're likely typos.
+func unmatchedSensors(patterns, sensors []string) []string {
+	var rv []string
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "!") {
+			continue
+		}
+		matched := false
+		for _, s := range sensors {
+			if utils.Glob(p, s) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			rv = append(rv, p)
+		}
+	}
+	return rv
+}
+
 func assignColors(data map[string][]float64, colors []int, assign map[string]ui.Color) {
 	// Make sure the data is always processed in the same order so that
 	// colors are assigned to devices consistently
@@ -270,73 +603,62 @@ func countMaxHeight(rs [][]widgetRule) int {
 	return ttl
 }
 
-// deepFindWidget looks in the UI widget tree for a widget, and returns it if found or nil if not.
-func deepFindWidget(gs interface{}, test func(v interface{}) interface{}) interface{} {
+// deepFindScalable looks in the UI widget tree for Scalable widgets,
+// and returns them if found or an empty slice if not.
+func deepFindScalable(gs interface{}) []widgets.Scalable {
 	// Recursive function #1.  Recursion is OK here because the number
 	// of UI elements, even in a very complex UI, is going to be
 	// relatively small.
 	t, ok := gs.(ui.GridItem)
 	if ok {
-		return deepFindWidget(t.Entry, test)
+		return deepFindScalable(t.Entry)
 	}
 	es, ok := gs.([]ui.GridItem)
+	rvs := make([]widgets.Scalable, 0)
 	if ok {
 		for _, g := range es {
-			v := deepFindWidget(g, test)
-			if v != nil {
-				return v
-			}
+			vs := deepFindScalable(g)
+			rvs = append(rvs, vs...)
 		}
+		return rvs
 	}
 	fs, ok := gs.([]interface{})
 	if ok {
 		for _, g := range fs {
-			v := deepFindWidget(g, test)
-			if v != nil {
-				return v
-			}
+			vs := deepFindScalable(g)
+			rvs = append(rvs, vs...)
 		}
+		return rvs
 	}
-	fs2, ok := gs.([][]interface{})
+	p, ok := gs.(widgets.Scalable)
 	if ok {
-		for _, g := range fs2 {
-			v := deepFindWidget(g, test)
-			if v != nil {
-				return v
-			}
-		}
+		rvs = append(rvs, p)
 	}
-	return test(gs)
+	return rvs
 }
 
-// deepFindScalable looks in the UI widget tree for Scalable widgets,
-// and returns them if found or an empty slice if not.
-func deepFindScalable(gs interface{}) []widgets.Scalable {
-	// Recursive function #1.  See the comment in deepFindProc.
+// deepFindAll returns every widget in the UI widget tree, in the order they
+// appear in the layout.
+func deepFindAll(gs interface{}) []interface{} {
+	// Recursive function #1.  See the comment in deepFindScalable.
 	t, ok := gs.(ui.GridItem)
 	if ok {
-		return deepFindScalable(t.Entry)
+		return deepFindAll(t.Entry)
 	}
+	rvs := make([]interface{}, 0)
 	es, ok := gs.([]ui.GridItem)
-	rvs := make([]widgets.Scalable, 0)
 	if ok {
 		for _, g := range es {
-			vs := deepFindScalable(g)
-			rvs = append(rvs, vs...)
+			rvs = append(rvs, deepFindAll(g)...)
 		}
 		return rvs
 	}
 	fs, ok := gs.([]interface{})
 	if ok {
 		for _, g := range fs {
-			vs := deepFindScalable(g)
-			rvs = append(rvs, vs...)
+			rvs = append(rvs, deepFindAll(g)...)
 		}
 		return rvs
 	}
-	p, ok := gs.(widgets.Scalable)
-	if ok {
-		rvs = append(rvs, p)
-	}
-	return rvs
+	return append(rvs, gs)
 }
@@ -2,7 +2,10 @@ package layout
 
 // NOT MY FAULT.  Some dependency already pulled in testify -- 13kLOC
 import (
+	ui "github.com/gizak/termui/v3"
 	"github.com/stretchr/testify/assert"
+	"github.com/xxxserxxx/gotop/v4"
+	"github.com/xxxserxxx/gotop/v4/termui"
 	"strings"
 	"testing"
 )
@@ -13,120 +16,154 @@ func TestParsing(t *testing.T) {
 		f func(l layout)
 	}{
 		{"cpu", func(l layout) {
-			assert.Equal(t, 1, len(l.Rows))
-			assert.Equal(t, 1, len(l.Rows[0]))
+			assert.Equal(t, 1, len(l.Pages[0].Rows))
+			assert.Equal(t, 1, len(l.Pages[0].Rows[0]))
 		}},
 		{"   cpu   \ndisk/1     mem/3\ntemp   \nnet    procs", func(l layout) {
-			assert.Equal(t, 4, len(l.Rows))
-			assert.Equal(t, 1, len(l.Rows[0]))
-			assert.Equal(t, 2, len(l.Rows[1]))
-			assert.Equal(t, 1, len(l.Rows[2]))
-			assert.Equal(t, 2, len(l.Rows[3]))
+			assert.Equal(t, 4, len(l.Pages[0].Rows))
+			assert.Equal(t, 1, len(l.Pages[0].Rows[0]))
+			assert.Equal(t, 2, len(l.Pages[0].Rows[1]))
+			assert.Equal(t, 1, len(l.Pages[0].Rows[2]))
+			assert.Equal(t, 2, len(l.Pages[0].Rows[3]))
 		}},
 		{"cpu\ndisk/1 mem/3\ntemp\nnet procs", func(l layout) {
-			assert.Equal(t, 4, len(l.Rows))
+			assert.Equal(t, 4, len(l.Pages[0].Rows))
 			// 1
-			assert.Equal(t, 1, len(l.Rows[0]))
-			assert.Equal(t, 1.0, l.Rows[0][0].Weight)
-			assert.Equal(t, 1, l.Rows[0][0].Height)
+			assert.Equal(t, 1, len(l.Pages[0].Rows[0]))
+			assert.Equal(t, 1.0, l.Pages[0].Rows[0][0].Weight)
+			assert.Equal(t, 1, l.Pages[0].Rows[0][0].Height)
 			// 2
-			assert.Equal(t, 2, len(l.Rows[1]))
-			assert.Equal(t, 1.0/4, l.Rows[1][0].Weight)
-			assert.Equal(t, 1, l.Rows[1][0].Height)
-			assert.Equal(t, 3.0/4, l.Rows[1][1].Weight)
-			assert.Equal(t, 1, l.Rows[1][1].Height)
+			assert.Equal(t, 2, len(l.Pages[0].Rows[1]))
+			assert.Equal(t, 1.0/4, l.Pages[0].Rows[1][0].Weight)
+			assert.Equal(t, 1, l.Pages[0].Rows[1][0].Height)
+			assert.Equal(t, 3.0/4, l.Pages[0].Rows[1][1].Weight)
+			assert.Equal(t, 1, l.Pages[0].Rows[1][1].Height)
 			// 3
-			assert.Equal(t, 1, len(l.Rows[2]))
-			assert.Equal(t, 1.0, l.Rows[2][0].Weight)
-			assert.Equal(t, 1, l.Rows[2][0].Height)
+			assert.Equal(t, 1, len(l.Pages[0].Rows[2]))
+			assert.Equal(t, 1.0, l.Pages[0].Rows[2][0].Weight)
+			assert.Equal(t, 1, l.Pages[0].Rows[2][0].Height)
 			// 4
-			assert.Equal(t, 2, len(l.Rows[3]))
-			assert.Equal(t, 0.5, l.Rows[3][0].Weight)
-			assert.Equal(t, 1, l.Rows[3][0].Height)
-			assert.Equal(t, 0.5, l.Rows[3][1].Weight)
-			assert.Equal(t, 1, l.Rows[3][1].Height)
+			assert.Equal(t, 2, len(l.Pages[0].Rows[3]))
+			assert.Equal(t, 0.5, l.Pages[0].Rows[3][0].Weight)
+			assert.Equal(t, 1, l.Pages[0].Rows[3][0].Height)
+			assert.Equal(t, 0.5, l.Pages[0].Rows[3][1].Weight)
+			assert.Equal(t, 1, l.Pages[0].Rows[3][1].Height)
 		}},
 		{"2:cpu\ndisk\nmem", func(l layout) {
-			assert.Equal(t, 3, len(l.Rows))
-			assert.Equal(t, 1, len(l.Rows[0]))
-			assert.Equal(t, 2, l.Rows[0][0].Height)
-			assert.Equal(t, 1, len(l.Rows[1]))
-			assert.Equal(t, 1, l.Rows[1][0].Height)
-			assert.Equal(t, 1, len(l.Rows[2]))
-			assert.Equal(t, 1, l.Rows[2][0].Height)
+			assert.Equal(t, 3, len(l.Pages[0].Rows))
+			assert.Equal(t, 1, len(l.Pages[0].Rows[0]))
+			assert.Equal(t, 2, l.Pages[0].Rows[0][0].Height)
+			assert.Equal(t, 1, len(l.Pages[0].Rows[1]))
+			assert.Equal(t, 1, l.Pages[0].Rows[1][0].Height)
+			assert.Equal(t, 1, len(l.Pages[0].Rows[2]))
+			assert.Equal(t, 1, l.Pages[0].Rows[2][0].Height)
 		}},
 		{"2:cpu disk\nmem", func(l layout) {
-			assert.Equal(t, 2, len(l.Rows))
-			assert.Equal(t, 2, len(l.Rows[0]))
-			assert.Equal(t, 2, l.Rows[0][0].Height)
-			assert.Equal(t, 1, l.Rows[0][1].Height)
-			assert.Equal(t, 1, len(l.Rows[1]))
-			assert.Equal(t, 1, l.Rows[1][0].Height)
+			assert.Equal(t, 2, len(l.Pages[0].Rows))
+			assert.Equal(t, 2, len(l.Pages[0].Rows[0]))
+			assert.Equal(t, 2, l.Pages[0].Rows[0][0].Height)
+			assert.Equal(t, 1, l.Pages[0].Rows[0][1].Height)
+			assert.Equal(t, 1, len(l.Pages[0].Rows[1]))
+			assert.Equal(t, 1, l.Pages[0].Rows[1][0].Height)
 		}},
 		{"cpu 2:disk\nmem", func(l layout) {
-			assert.Equal(t, 2, len(l.Rows))
-			assert.Equal(t, 2, len(l.Rows[0]))
-			assert.Equal(t, 1, l.Rows[0][0].Height)
-			assert.Equal(t, 2, l.Rows[0][1].Height)
-			assert.Equal(t, 1, len(l.Rows[1]))
-			assert.Equal(t, 1, l.Rows[1][0].Height)
+			assert.Equal(t, 2, len(l.Pages[0].Rows))
+			assert.Equal(t, 2, len(l.Pages[0].Rows[0]))
+			assert.Equal(t, 1, l.Pages[0].Rows[0][0].Height)
+			assert.Equal(t, 2, l.Pages[0].Rows[0][1].Height)
+			assert.Equal(t, 1, len(l.Pages[0].Rows[1]))
+			assert.Equal(t, 1, l.Pages[0].Rows[1][0].Height)
 		}},
 		{"cpu disk\n2:mem", func(l layout) {
-			assert.Equal(t, 2, len(l.Rows))
-			assert.Equal(t, 2, len(l.Rows[0]))
-			assert.Equal(t, 1, l.Rows[0][0].Height)
-			assert.Equal(t, 1, l.Rows[0][1].Height)
-			assert.Equal(t, 1, len(l.Rows[1]))
-			assert.Equal(t, 2, l.Rows[1][0].Height)
+			assert.Equal(t, 2, len(l.Pages[0].Rows))
+			assert.Equal(t, 2, len(l.Pages[0].Rows[0]))
+			assert.Equal(t, 1, l.Pages[0].Rows[0][0].Height)
+			assert.Equal(t, 1, l.Pages[0].Rows[0][1].Height)
+			assert.Equal(t, 1, len(l.Pages[0].Rows[1]))
+			assert.Equal(t, 2, l.Pages[0].Rows[1][0].Height)
 		}},
 		{"cpu 2:disk/3\nmem", func(l layout) {
-			assert.Equal(t, 2, len(l.Rows))
-			assert.Equal(t, 2, len(l.Rows[0]))
-			assert.Equal(t, 1, l.Rows[0][0].Height)
-			assert.Equal(t, 1.0/4, l.Rows[0][0].Weight)
-			assert.Equal(t, 2, l.Rows[0][1].Height)
-			assert.Equal(t, 3.0/4, l.Rows[0][1].Weight)
-			assert.Equal(t, 1, len(l.Rows[1]))
-			assert.Equal(t, 1, l.Rows[1][0].Height)
-			assert.Equal(t, 1.0, l.Rows[1][0].Weight)
+			assert.Equal(t, 2, len(l.Pages[0].Rows))
+			assert.Equal(t, 2, len(l.Pages[0].Rows[0]))
+			assert.Equal(t, 1, l.Pages[0].Rows[0][0].Height)
+			assert.Equal(t, 1.0/4, l.Pages[0].Rows[0][0].Weight)
+			assert.Equal(t, 2, l.Pages[0].Rows[0][1].Height)
+			assert.Equal(t, 3.0/4, l.Pages[0].Rows[0][1].Weight)
+			assert.Equal(t, 1, len(l.Pages[0].Rows[1]))
+			assert.Equal(t, 1, l.Pages[0].Rows[1][0].Height)
+			assert.Equal(t, 1.0, l.Pages[0].Rows[1][0].Weight)
 		}},
 		{"2:cpu disk\nmem/3", func(l layout) {
-			assert.Equal(t, 2, len(l.Rows))
-			assert.Equal(t, 2, len(l.Rows[0]))
-			assert.Equal(t, 2, l.Rows[0][0].Height)
-			assert.Equal(t, 0.5, l.Rows[0][0].Weight)
-			assert.Equal(t, 1, l.Rows[0][1].Height)
-			assert.Equal(t, 0.5, l.Rows[0][1].Weight)
-			assert.Equal(t, 1, len(l.Rows[1]))
-			assert.Equal(t, 1, l.Rows[1][0].Height)
-			assert.Equal(t, 1.0, l.Rows[1][0].Weight)
+			assert.Equal(t, 2, len(l.Pages[0].Rows))
+			assert.Equal(t, 2, len(l.Pages[0].Rows[0]))
+			assert.Equal(t, 2, l.Pages[0].Rows[0][0].Height)
+			assert.Equal(t, 0.5, l.Pages[0].Rows[0][0].Weight)
+			assert.Equal(t, 1, l.Pages[0].Rows[0][1].Height)
+			assert.Equal(t, 0.5, l.Pages[0].Rows[0][1].Weight)
+			assert.Equal(t, 1, len(l.Pages[0].Rows[1]))
+			assert.Equal(t, 1, l.Pages[0].Rows[1][0].Height)
+			assert.Equal(t, 1.0, l.Pages[0].Rows[1][0].Weight)
 		}},
 		{"cpu/2 mem/1 6:procs\n3:temp/1 2:disk/2\npower\nnet procs", func(l layout) {
-			assert.Equal(t, 4, len(l.Rows))
+			assert.Equal(t, 4, len(l.Pages[0].Rows))
 			// First row
-			assert.Equal(t, 3, len(l.Rows[0]))
-			assert.Equal(t, 1, l.Rows[0][0].Height)
-			assert.Equal(t, 0.5, l.Rows[0][0].Weight)
-			assert.Equal(t, 1, l.Rows[0][1].Height)
-			assert.Equal(t, 0.25, l.Rows[0][1].Weight)
-			assert.Equal(t, 6, l.Rows[0][2].Height)
-			assert.Equal(t, 0.25, l.Rows[0][2].Weight)
+			assert.Equal(t, 3, len(l.Pages[0].Rows[0]))
+			assert.Equal(t, 1, l.Pages[0].Rows[0][0].Height)
+			assert.Equal(t, 0.5, l.Pages[0].Rows[0][0].Weight)
+			assert.Equal(t, 1, l.Pages[0].Rows[0][1].Height)
+			assert.Equal(t, 0.25, l.Pages[0].Rows[0][1].Weight)
+			assert.Equal(t, 6, l.Pages[0].Rows[0][2].Height)
+			assert.Equal(t, 0.25, l.Pages[0].Rows[0][2].Weight)
 			// Second row
-			assert.Equal(t, 2, len(l.Rows[1]))
-			assert.Equal(t, 3, l.Rows[1][0].Height)
-			assert.Equal(t, 1/3.0, l.Rows[1][0].Weight)
-			assert.Equal(t, 2, l.Rows[1][1].Height)
-			assert.Equal(t, 2/3.0, l.Rows[1][1].Weight)
+			assert.Equal(t, 2, len(l.Pages[0].Rows[1]))
+			assert.Equal(t, 3, l.Pages[0].Rows[1][0].Height)
+			assert.Equal(t, 1/3.0, l.Pages[0].Rows[1][0].Weight)
+			assert.Equal(t, 2, l.Pages[0].Rows[1][1].Height)
+			assert.Equal(t, 2/3.0, l.Pages[0].Rows[1][1].Weight)
 			// Third row
-			assert.Equal(t, 1, len(l.Rows[2]))
-			assert.Equal(t, 1, l.Rows[2][0].Height)
-			assert.Equal(t, 1.0, l.Rows[2][0].Weight)
+			assert.Equal(t, 1, len(l.Pages[0].Rows[2]))
+			assert.Equal(t, 1, l.Pages[0].Rows[2][0].Height)
+			assert.Equal(t, 1.0, l.Pages[0].Rows[2][0].Weight)
 			// Fourth row
-			assert.Equal(t, 2, len(l.Rows[3]))
-			assert.Equal(t, 1, l.Rows[3][0].Height)
-			assert.Equal(t, 0.5, l.Rows[3][0].Weight)
-			assert.Equal(t, 1, l.Rows[3][1].Height)
-			assert.Equal(t, 0.5, l.Rows[3][1].Weight)
+			assert.Equal(t, 2, len(l.Pages[0].Rows[3]))
+			assert.Equal(t, 1, l.Pages[0].Rows[3][0].Height)
+			assert.Equal(t, 0.5, l.Pages[0].Rows[3][0].Weight)
+			assert.Equal(t, 1, l.Pages[0].Rows[3][1].Height)
+			assert.Equal(t, 0.5, l.Pages[0].Rows[3][1].Weight)
+		}},
+		{"2:net[iface=eth0,mbps]/2 net[iface=wlan0] cpu[avg]\ntemp[sensors=coretemp*, nvme*] disk[mounts=/,/home]", func(l layout) {
+			assert.Equal(t, 2, len(l.Pages[0].Rows))
+			assert.Equal(t, 3, len(l.Pages[0].Rows[0]))
+			assert.Equal(t, "net", l.Pages[0].Rows[0][0].Widget)
+			assert.Equal(t, 2, l.Pages[0].Rows[0][0].Height)
+			assert.Equal(t, 0.5, l.Pages[0].Rows[0][0].Weight)
+			assert.Equal(t, map[string]string{"iface": "eth0", "mbps": ""}, l.Pages[0].Rows[0][0].Options)
+			assert.Equal(t, map[string]string{"iface": "wlan0"}, l.Pages[0].Rows[0][1].Options)
+			assert.Equal(t, map[string]string{"avg": ""}, l.Pages[0].Rows[0][2].Options)
+			assert.Equal(t, 2, len(l.Pages[0].Rows[1]))
+			assert.Equal(t, "temp", l.Pages[0].Rows[1][0].Widget)
+			assert.Equal(t, map[string]string{"sensors": "coretemp*,nvme*"}, l.Pages[0].Rows[1][0].Options)
+			assert.Equal(t, map[string]string{"mounts": "/,/home"}, l.Pages[0].Rows[1][1].Options)
+		}},
+		{"procs disk", func(l layout) {
+			assert.Nil(t, l.Pages[0].Rows[0][0].Options)
+		}},
+		{"cpu\n--- procs\nprocs\n---\n---  storage \ndisk temp\nnet", func(l layout) {
+			assert.Equal(t, 3, len(l.Pages))
+			assert.Equal(t, "", l.Pages[0].Name)
+			assert.Equal(t, 1, len(l.Pages[0].Rows))
+			assert.Equal(t, "procs", l.Pages[1].Name)
+			assert.Equal(t, "procs", l.Pages[1].Rows[0][0].Widget)
+			assert.Equal(t, "storage", l.Pages[2].Name)
+			assert.Equal(t, 2, len(l.Pages[2].Rows))
+		}},
+		{"--- main\ncpu", func(l layout) {
+			assert.Equal(t, 1, len(l.Pages))
+			assert.Equal(t, "main", l.Pages[0].Name)
+		}},
+		{"", func(l layout) {
+			assert.Equal(t, 1, len(l.Pages))
+			assert.Equal(t, 0, len(l.Pages[0].Rows))
 		}},
 	}
 
@@ -136,3 +173,89 @@ func TestParsing(t *testing.T) {
 		tc.f(l)
 	}
 }
+
+// keyWidget is an interactive widget that handles a set of keys.
+type keyWidget struct {
+	*ui.Block
+	keys    string
+	handled []string
+}
+
+func (k *keyWidget) HandleEvent(e ui.Event) bool {
+	if !strings.Contains(k.keys, e.ID) {
+		return false
+	}
+	k.handled = append(k.handled, e.ID)
+	return true
+}
+
+func TestHandleEvent(t *testing.T) {
+	a := &keyWidget{Block: ui.NewBlock(), keys: "jk"}
+	b := &keyWidget{Block: ui.NewBlock(), keys: "jb"}
+	plain := ui.NewBlock()
+	g := ui.NewGrid()
+	g.Set(ui.NewRow(1, ui.NewCol(1.0/3, plain), ui.NewCol(1.0/3, a), ui.NewCol(1.0/3, b)))
+	grid := &MyGrid{pages: []*gridPage{{grid: g}}}
+	grid.SetPage(0)
+	grid.SetRect(0, 0, 90, 10)
+	grid.Draw(ui.NewBuffer(grid.GetRect()))
+
+	// Nothing handles x; the unfocused a handles j, and is focused
+	assert.False(t, grid.HandleEvent(ui.Event{ID: "x"}))
+	assert.True(t, grid.HandleEvent(ui.Event{ID: "j"}))
+	assert.Equal(t, a, grid.Focused())
+	// b is offered b, and keeps the focus for j
+	assert.True(t, grid.HandleEvent(ui.Event{ID: "b"}))
+	assert.True(t, grid.HandleEvent(ui.Event{ID: "j"}))
+	assert.Equal(t, []string{"j"}, a.handled)
+	assert.Equal(t, []string{"b", "j"}, b.handled)
+	// Clicking a non-interactive widget focuses it
+	assert.True(t, grid.HandleEvent(ui.Event{ID: "<MouseLeft>", Payload: ui.Mouse{X: 5, Y: 5}}))
+	assert.Equal(t, plain, grid.Focused())
+	// Only the zoomed widget gets events
+	grid.ToggleZoom()
+	assert.False(t, grid.HandleEvent(ui.Event{ID: "k"}))
+	grid.ToggleZoom()
+	assert.True(t, grid.HandleEvent(ui.Event{ID: "k"}))
+	grid.FocusNext()
+	assert.Equal(t, b, grid.Focused())
+}
+
+func TestSparklineOptions(t *testing.T) {
+	label := func(v int) string { return "max" }
+	tests := []struct {
+		c       gotop.Config
+		options map[string]string
+		f       func(g *termui.SparklineGroup)
+	}{
+		{gotop.Config{}, nil, func(g *termui.SparklineGroup) {
+			assert.False(t, g.Log)
+			assert.False(t, g.Shared)
+			assert.Nil(t, g.MaxLabel)
+			assert.Equal(t, 0, g.Max)
+		}},
+		{gotop.Config{SparklineLog: true, SparklineLegend: true}, nil, func(g *termui.SparklineGroup) {
+			assert.True(t, g.Log)
+			assert.False(t, g.Shared)
+			assert.NotNil(t, g.MaxLabel)
+		}},
+		{gotop.Config{}, map[string]string{"shared": "", "log": "", "max": "119M"}, func(g *termui.SparklineGroup) {
+			assert.True(t, g.Log)
+			assert.True(t, g.Shared)
+			assert.Nil(t, g.MaxLabel)
+			assert.Equal(t, 119<<20, g.Max)
+		}},
+	}
+	for _, k := range tests {
+		g := termui.NewSparklineGroup()
+		setSparklineOptions(k.c, widgetRule{Widget: "net", Options: k.options}, g, label)
+		k.f(g)
+	}
+}
+
+func TestUnmatchedSensors(t *testing.T) {
+	sensors := []string{"coretemp_core0", "coretemp_core1", "nvme_composite"}
+	assert.Empty(t, unmatchedSensors([]string{"coretemp*", "nvme*", "!nvme*"}, sensors))
+	assert.Equal(t, []string{"cortemp*", "acpitz"}, unmatchedSensors([]string{"cortemp*", "nvme*", "acpitz", "!nope"}, sensors))
+	assert.Empty(t, unmatchedSensors(nil, sensors))
+}
@@ -6,17 +6,18 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 /**********************************************************************************
 The syntax for the layout specification is:
 ```
-(rowspan:)?widget(/weight)?
+(rowspan:)?widget([options])?(/weight)?
 ```
 1. Each line is a row
 2. Empty lines are skipped
 3. Spaces are compressed
-4. Legal widget names are: cpu, disk, mem, temp, batt, net, procs
+4. Legal widget names are: cpu, disk, diskio, smart, mem, temp, batt, net, netif, procs, hosts, conns, ports
 5. Names are not case sensitive
 4. The simplest row is a single widget, by name, e.g.
    ```
@@ -62,21 +63,65 @@ The syntax for the layout specification is:
     spacers will be used.
 15. Lines beginning with "#" will be ignored. It must be the first character of
     the line.
+16. Options in brackets after the widget name override the configuration for
+    that widget only; they're separated by commas, and are either flags or
+    NAME=VALUE.  Values can be lists, which are also separated by commas, e.g.
+    ```
+    net[iface=eth0,bits] net[iface=wlan0] temp[sensors=coretemp*,nvme*]
+    ```
+    Options may contain spaces.  Unknown options are logged and ignored.
+17. A line beginning with "---" starts a new page, named by the rest of the
+    line. Rows before the first such line are on an unnamed first page. Only
+    one page is displayed at a time, e.g.
+    ```
+    --- overview
+    cpu
+    mem net
+    --- processes
+    procs
+    ```
 **********************************************************************************/
+
+// widgetOptions are the options each widget accepts in a layout; true if the
+// option takes a value, false if it's a flag.
+var widgetOptions = map[string]map[string]bool{
+	"cpu":    {"avg": false, "percpu": false},
+	"net":    {"iface": true, "bits": false, "mbps": false, "bytes": false, "packets": false, "log": false, "shared": false, "legend": false, "max": true},
+	"netif":  {"iface": true, "log": false, "shared": false, "legend": false, "max": true},
+	"diskio": {"log": false, "shared": false, "legend": false},
+	"temp":   {"sensors": true},
+	"disk":   {"mounts": true, "columns": true},
+}
 func ParseLayout(i io.Reader) layout {
 	r := bufio.NewScanner(i)
-	rv := layout{Rows: make([][]widgetRule, 0)}
+	rv := layout{}
+	pg := page{Rows: make([][]widgetRule, 0)}
 	var lineNo int
 	for r.Scan() {
 		l := strings.TrimSpace(r.Text())
 		if l == "" || l[0] == '#' {
 			continue
 		}
+		if strings.HasPrefix(l, "---") {
+			if len(pg.Rows) > 0 {
+				rv.Pages = append(rv.Pages, pg)
+			}
+			pg = page{Name: strings.TrimSpace(strings.TrimLeft(l, "-")), Rows: make([][]widgetRule, 0)}
+			continue
+		}
 		row := make([]widgetRule, 0)
-		ws := strings.Fields(l)
+		ws := splitWidgets(l)
 		weightTotal := 0
 		for _, w := range ws {
 			wr := widgetRule{Weight: 1}
+			// Options may contain slashes and colons, so they're taken out first
+			var opts string
+			if i := strings.Index(w, "["); i > -1 {
+				if j := strings.LastIndex(w, "]"); j > i {
+					opts = w[i+1 : j]
+					w = w[:i] + w[j+1:]
+				}
+			}
 			ks := strings.Split(w, "/")
 			rs := strings.Split(ks[0], ":")
 			var wid string
@@ -97,6 +142,9 @@ func ParseLayout(i io.Reader) layout {
 				wid = rs[0]
 			}
 			wr.Widget = strings.ToLower(wid)
+			if opts != "" {
+				wr.Options = parseOptions(wr.Widget, opts)
+			}
 			if len(ks) > 1 {
 				weight, e := strconv.Atoi(ks[1])
 				if e != nil {
@@ -125,7 +173,63 @@ func ParseLayout(i io.Reader) layout {
 		for i, w := range row {
 			row[i].Weight = w.Weight / float64(weightTotal)
 		}
-		rv.Rows = append(rv.Rows, row)
+		pg.Rows = append(pg.Rows, row)
+	}
+	if len(pg.Rows) > 0 || len(rv.Pages) == 0 {
+		rv.Pages = append(rv.Pages, pg)
+	}
+	return rv
+}
+
+// splitWidgets splits a row at spaces that aren't in options.
+func splitWidgets(l string) []string {
+	var ws []string
+	var depth int
+	start := -1
+	for i, r := range l {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']' && depth > 0:
+			depth--
+		case unicode.IsSpace(r) && depth == 0:
+			if start > -1 {
+				ws = append(ws, l[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start > -1 {
+		ws = append(ws, l[start:])
+	}
+	return ws
+}
+
+// parseOptions parses the options of a widget. Flags have empty values. An
+// item without an = that isn't one of the widget's flags continues the list
+// value of the option before it.
+func parseOptions(widget, opts string) map[string]string {
+	rv := make(map[string]string)
+	var last string
+	for _, item := range strings.Split(opts, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if i := strings.Index(item, "="); i > -1 {
+			last = strings.ToLower(strings.TrimSpace(item[:i]))
+			rv[last] = strings.TrimSpace(item[i+1:])
+			continue
+		}
+		if valued, ok := widgetOptions[widget][strings.ToLower(item)]; (ok && !valued) || last == "" {
+			rv[strings.ToLower(item)] = ""
+			continue
+		}
+		rv[last] += "," + item
 	}
 	return rv
 }
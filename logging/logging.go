@@ -27,12 +27,7 @@ func New(c gotop.Config) (io.WriteCloser, error) {
 	if err != nil && !os.IsExist(err) {
 		return nil, err
 	}
-	w := &RotateWriter{
-		filename:   filepath.Join(cache.Path, LOGFILE),
-		maxLogSize: c.MaxLogSize,
-		tr:         c.Tr,
-	}
-	err = w.rotate()
+	w, err := NewRotateWriter(filepath.Join(cache.Path, LOGFILE), c.MaxLogSize, nil, c.Tr)
 	if err != nil {
 		return nil, err
 	}
@@ -50,9 +45,26 @@ type RotateWriter struct {
 	filename   string // should be set to the actual filename
 	fp         *os.File
 	maxLogSize int64
+	header     []byte
 	tr         lingo.Translations
 }
 
+// NewRotateWriter opens filename for writing, moving an existing file to the
+// first numbered backup. The file is rotated when it grows beyond maxSize
+// bytes, and header, if any, is written at the start of every file.
+func NewRotateWriter(filename string, maxSize int64, header []byte, tr lingo.Translations) (*RotateWriter, error) {
+	w := &RotateWriter{
+		filename:   filename,
+		maxLogSize: maxSize,
+		header:     header,
+		tr:         tr,
+	}
+	if err := w.rotate(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
 func (w *RotateWriter) Close() error {
 	return w.fp.Close()
 }
@@ -108,6 +120,9 @@ func (w *RotateWriter) rotate() (err error) {
 	if err != nil {
 		return fmt.Errorf(w.tr.Value("error.logopen", w.filename, err.Error()))
 	}
+	if len(w.header) > 0 {
+		_, err = w.fp.Write(w.header)
+	}
 
-	return nil
+	return err
 }
@@ -3,6 +3,7 @@ package termui
 import (
 	"image"
 	"log"
+	"math"
 
 	. "github.com/gizak/termui/v3"
 )
@@ -14,12 +15,28 @@ type Sparkline struct {
 	Title2     string
 	TitleColor Color
 	LineColor  Color
+	// Title3 is shown below the others if there's room, in Title3Color, or
+	// TitleColor if that's ColorClear.
+	Title3      string
+	Title3Color Color
 }
 
 // SparklineGroup is a renderable widget which groups together the given sparklines.
 type SparklineGroup struct {
 	*Block
 	Lines []*Sparkline
+	// Log scales the bars logarithmically, so that a burst doesn't flatten
+	// the rest of the graph.
+	Log bool
+	// Max is the value of a full bar; larger values are drawn as full bars.
+	// If it's 0, a full bar is the largest value in view.
+	Max int
+	// Shared scales all the lines to the largest value in view of any of
+	// them, so that they can be compared.
+	Shared bool
+	// MaxLabel formats the value of a full bar, which is then shown at the
+	// right of the first title of each line.
+	MaxLabel func(int) string
 }
 
 // Add appends a given Sparkline to the *SparklineGroup.
@@ -29,7 +46,7 @@ func (self *SparklineGroup) Add(sl Sparkline) {
 
 // NewSparkline returns an unrenderable single sparkline that intended to be added into a SparklineGroup.
 func NewSparkline() *Sparkline {
-	return &Sparkline{}
+	return &Sparkline{Title3Color: ColorClear}
 }
 
 // NewSparklineGroup return a new *SparklineGroup with given Sparklines, you can always add a new Sparkline later.
@@ -51,7 +68,20 @@ func (self *SparklineGroup) Draw(buf *Buffer) {
 		// prints titles
 		title1Y := self.Inner.Min.Y + 1 + (self.Inner.Dy()/lc)*i
 		title2Y := self.Inner.Min.Y + 2 + (self.Inner.Dy()/lc)*i
-		title1 := TrimString(line.Title1, self.Inner.Dx())
+		max := self.scale(line)
+		// the legend shares the first title's row, so the title is trimmed to
+		// leave room for it, and it's dropped if there isn't room for both
+		var label string
+		title1Width := self.Inner.Dx()
+		if self.MaxLabel != nil {
+			label = self.MaxLabel(max)
+			if n := len([]rune(label)); n+1 < title1Width {
+				title1Width -= n + 1
+			} else {
+				label = ""
+			}
+		}
+		title1 := TrimString(line.Title1, title1Width)
 		title2 := TrimString(line.Title2, self.Inner.Dx())
 		if self.Inner.Dy() > 5 {
 			buf.SetString(
@@ -68,13 +98,25 @@ func (self *SparklineGroup) Draw(buf *Buffer) {
 			)
 		}
 
-		sparkY := (self.Inner.Dy() / lc) * (i + 1)
-		// finds max data in current view used for relative heights
-		max := 1
-		for i := len(line.Data) - 1; i >= 0 && self.Inner.Dx()-((len(line.Data)-1)-i) >= 1; i-- {
-			if line.Data[i] > max {
-				max = line.Data[i]
+		if line.Title3 != "" && self.Inner.Dy()/lc > 4 {
+			color := line.Title3Color
+			if color == ColorClear {
+				color = line.TitleColor
 			}
+			buf.SetString(
+				TrimString(line.Title3, self.Inner.Dx()),
+				NewStyle(color, ColorClear, ModifierBold),
+				image.Pt(self.Inner.Min.X, title2Y+1),
+			)
+		}
+
+		sparkY := (self.Inner.Dy() / lc) * (i + 1)
+		if label != "" && self.Inner.Dy() > 5 {
+			buf.SetString(
+				label,
+				NewStyle(line.TitleColor, ColorClear, ModifierBold),
+				image.Pt(self.Inner.Max.X-len([]rune(label)), title1Y),
+			)
 		}
 		// prints sparkline
 		for x := self.Inner.Dx(); x >= 1; x-- {
@@ -82,15 +124,10 @@ func (self *SparklineGroup) Draw(buf *Buffer) {
 			if (self.Inner.Dx() - x) < len(line.Data) {
 				offset := self.Inner.Dx() - x
 				curItem := line.Data[(len(line.Data)-1)-offset]
-				percent := float64(curItem) / float64(max)
-				index := int(percent*float64(len(BARS)-2)) + 1
-				if index < 1 || index >= len(BARS) {
-					log.Printf(
-						"invalid sparkline data value. index: %v, percent: %v, curItem: %v, offset: %v",
-						index, percent, curItem, offset,
-					)
+				if curItem < 0 {
+					log.Printf("invalid sparkline data value. curItem: %v, offset: %v", curItem, offset)
 				} else {
-					char = BARS[index]
+					char = BARS[barIndex(curItem, max, self.Log)]
 				}
 			}
 			buf.SetCell(
@@ -104,3 +141,49 @@ func (self *SparklineGroup) Draw(buf *Buffer) {
 		}
 	}
 }
+
+// scale returns the value of a full bar of the line: Max, if it's set, or
+// else the largest value in view of the line, or of all the lines if they're
+// Shared.
+func (self *SparklineGroup) scale(line *Sparkline) int {
+	if self.Max > 0 {
+		return self.Max
+	}
+	if !self.Shared {
+		return visibleMax(line.Data, self.Inner.Dx())
+	}
+	max := 1
+	for _, l := range self.Lines {
+		if m := visibleMax(l.Data, self.Inner.Dx()); m > max {
+			max = m
+		}
+	}
+	return max
+}
+
+// visibleMax is the largest of the last width data points, and at least 1.
+func visibleMax(data []int, width int) int {
+	max := 1
+	for i := len(data) - 1; i >= 0 && i >= len(data)-width; i-- {
+		if data[i] > max {
+			max = data[i]
+		}
+	}
+	return max
+}
+
+// barIndex is the index in BARS of the bar of v, where max is a full bar.
+// The smallest bar is drawn for 0, so that the line is visible.
+func barIndex(v, max int, logScale bool) int {
+	if v <= 0 || max <= 0 {
+		return 1
+	}
+	if v > max {
+		v = max
+	}
+	percent := float64(v) / float64(max)
+	if logScale {
+		percent = math.Log1p(float64(v)) / math.Log1p(float64(max))
+	}
+	return int(percent*float64(len(BARS)-2)) + 1
+}
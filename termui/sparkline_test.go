@@ -0,0 +1,77 @@
+package termui
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBarIndex(t *testing.T) {
+	tests := []struct {
+		v, max int
+		log    bool
+		e      int
+	}{
+		{v: 0, max: 100, e: 1},
+		{v: -5, max: 100, e: 1},
+		{v: 100, max: 100, e: 8},
+		{v: 50, max: 100, e: 4},
+		{v: 200, max: 100, e: 8},
+		{v: 1, max: 1000000, e: 1},
+		{v: 1000, max: 1000000, e: 1},
+		{v: 1000, max: 1000000, log: true, e: 4},
+		{v: 1000000, max: 1000000, log: true, e: 8},
+		{v: 0, max: 1000000, log: true, e: 1},
+	}
+	for _, k := range tests {
+		assert.Equal(t, k.e, barIndex(k.v, k.max, k.log), "%d of %d, log %t", k.v, k.max, k.log)
+	}
+}
+
+func TestSparklineScale(t *testing.T) {
+	rx, tx := NewSparkline(), NewSparkline()
+	rx.Data = []int{900, 10, 20, 30}
+	tx.Data = []int{5, 6, 7}
+	g := NewSparklineGroup(rx, tx)
+	g.SetRect(0, 0, 5, 10)
+
+	// The first RX point is out of view
+	assert.Equal(t, 30, g.scale(rx))
+	assert.Equal(t, 7, g.scale(tx))
+	g.Shared = true
+	assert.Equal(t, 30, g.scale(tx))
+	g.Max = 1000
+	assert.Equal(t, 1000, g.scale(rx))
+
+	g.Max, g.Shared = 0, false
+	g.SetRect(0, 0, 10, 10)
+	assert.Equal(t, 900, g.scale(rx))
+	assert.Equal(t, 1, visibleMax(nil, 10))
+}
+
+func TestSparklineLegend(t *testing.T) {
+	line := NewSparkline()
+	line.Title1 = " Total RX: 1.5 GiB"
+	g := NewSparklineGroup(line)
+	g.MaxLabel = func(int) string { return " max 1.0 MiB/s " }
+	row := func(width int) string {
+		g.SetRect(0, 0, width, 10)
+		buf := ui.NewBuffer(g.GetRect())
+		g.Draw(buf)
+		var b strings.Builder
+		for x := g.Inner.Min.X; x < g.Inner.Max.X; x++ {
+			b.WriteRune(buf.GetCell(image.Pt(x, g.Inner.Min.Y+1)).Rune)
+		}
+		return b.String()
+	}
+	// The title is trimmed to leave room for the legend
+	r := row(30)
+	assert.True(t, strings.HasSuffix(r, " max 1.0 MiB/s "), r)
+	assert.True(t, strings.HasPrefix(r, " Total RX: "), r)
+	// Without room for both, the legend is dropped
+	r = row(15)
+	assert.NotContains(t, r, "max", r)
+}
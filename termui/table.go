@@ -23,6 +23,9 @@ type Table struct {
 
 	ShowCursor  bool
 	CursorColor Color
+	// RowColors are the colours of rows, by index, drawn in the default
+	// colour otherwise
+	RowColors map[int]Color
 
 	ShowLocation bool
 
@@ -95,6 +98,9 @@ func (self *Table) Draw(buf *Buffer) {
 
 		// prints cursor
 		style := NewStyle(Theme.Default.Fg)
+		if c, ok := self.RowColors[rowNum]; ok {
+			style.Fg = c
+		}
 		if self.ShowCursor {
 			if (self.SelectedItem == "" && rowNum == self.SelectedRow) || (self.SelectedItem != "" && self.SelectedItem == row[self.UniqueCol]) {
 				style.Fg = self.CursorColor
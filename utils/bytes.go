@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"fmt"
 	"math"
+	"strconv"
+	"strings"
 )
 
 var (
@@ -11,6 +14,61 @@ var (
 	TB = uint64(math.Pow(2, 40))
 )
 
+// Units are how sizes and rates are shown. The zero value shows IEC units,
+// e.g. KiB and MiB, and rates in bytes per second.
+type Units struct {
+	// SI shows sizes in powers of 1000, e.g. kB and MB
+	SI bool
+	// Bits shows network rates in bits per second, e.g. Mib/s or Mb/s
+	Bits bool
+}
+
+// Names of the unit prefixes, as in the config file
+const (
+	UnitsIEC = "iec"
+	UnitsSI  = "si"
+)
+
+// The prefixes of the units, in increasing powers of 1024 or 1000
+var (
+	iecPrefixes = []string{"", "Ki", "Mi", "Gi", "Ti"}
+	siPrefixes  = []string{"", "k", "M", "G", "T"}
+)
+
+// units are the units of ConvertBytes and ConvertRate.
+var units Units
+
+// SetUnits sets the units of ConvertBytes and ConvertRate.
+func SetUnits(u Units) {
+	units = u
+}
+
+// GetUnits returns the units of ConvertBytes and ConvertRate.
+func GetUnits() Units {
+	return units
+}
+
+// Prefixes is the name of the unit prefixes, UnitsIEC or UnitsSI.
+func (u Units) Prefixes() string {
+	if u.SI {
+		return UnitsSI
+	}
+	return UnitsIEC
+}
+
+// SetPrefixes sets the unit prefixes by name, UnitsIEC or UnitsSI.
+func (u *Units) SetPrefixes(name string) error {
+	switch strings.ToLower(name) {
+	case UnitsIEC:
+		u.SI = false
+	case UnitsSI:
+		u.SI = true
+	default:
+		return fmt.Errorf("unknown units %q", name)
+	}
+	return nil
+}
+
 func CelsiusToFahrenheit(c int) int {
 	return c*9/5 + 32
 }
@@ -31,17 +89,82 @@ func BytesToTB(b uint64) float64 {
 	return float64(b) / float64(TB)
 }
 
+// ConvertBytes scales b to the largest unit that it's at least one of, e.g.
+// 1536 is 1.5 KiB, in the units set by SetUnits.
 func ConvertBytes(b uint64) (float64, string) {
-	switch {
-	case b < KB:
-		return float64(b), "B"
-	case b < MB:
-		return BytesToKB(b), "KB"
-	case b < GB:
-		return BytesToMB(b), "MB"
-	case b < TB:
-		return BytesToGB(b), "GB"
-	default:
-		return BytesToTB(b), "TB"
+	return units.Bytes(b)
+}
+
+// ConvertRate scales a network rate in bytes per second, in the units set by
+// SetUnits; the unit includes "/s".
+func ConvertRate(bytesPerSecond uint64) (float64, string) {
+	return units.Rate(bytesPerSecond)
+}
+
+// Bytes scales b to the largest unit that it's at least one of.
+func (u Units) Bytes(b uint64) (float64, string) {
+	v, prefix := u.scale(float64(b))
+	return v, prefix + "B"
+}
+
+// Rate scales a network rate in bytes per second, in bits if u.Bits is set.
+func (u Units) Rate(bytesPerSecond uint64) (float64, string) {
+	if !u.Bits {
+		v, unit := u.Bytes(bytesPerSecond)
+		return v, unit + "/s"
+	}
+	v, prefix := u.scale(float64(bytesPerSecond) * 8)
+	return v, prefix + "b/s"
+}
+
+// Multiplier is 1000 for SI units, and otherwise 1024.
+func (u Units) Multiplier() float64 {
+	if u.SI {
+		return 1000
+	}
+	return 1024
+}
+
+func (u Units) scale(v float64) (float64, string) {
+	prefixes := iecPrefixes
+	if u.SI {
+		prefixes = siPrefixes
+	}
+	m := u.Multiplier()
+	i := 0
+	for ; v >= m && i < len(prefixes)-1; i++ {
+		v /= m
+	}
+	return v, prefixes[i]
+}
+
+// ParseBytes parses a number of bytes, with an optional K, M, G, or T suffix
+// (with or without a B). The suffix is binary if it has an i, as in 100KiB,
+// and otherwise in the units set by SetUnits; e.g. 1.5M is 1.5 MiB, or with
+// SI units 1.5 MB.
+func ParseBytes(s string) (uint64, error) {
+	s = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B")
+	m, binary := units.Multiplier(), strings.HasSuffix(s, "I")
+	if binary {
+		s, m = s[:len(s)-1], 1024
+	}
+	mult := 1.0
+	if i := strings.IndexByte("KMGT", lastByte(s)); i >= 0 {
+		mult = math.Pow(m, float64(i+1))
+		s = s[:len(s)-1]
+	} else if binary {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return uint64(v * mult), nil
+}
+
+func lastByte(s string) byte {
+	if s == "" {
+		return 0
 	}
+	return s[len(s)-1]
 }
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		in  string
+		e   uint64
+		err bool
+	}{
+		{in: "0", e: 0},
+		{in: "512", e: 512},
+		{in: "512B", e: 512},
+		{in: "2K", e: 2048},
+		{in: "1.5kb", e: 1536},
+		{in: "119M", e: 119 * MB},
+		{in: " 1G ", e: GB},
+		{in: "2TB", e: 2 * TB},
+		{in: "", err: true},
+		{in: "M", err: true},
+		{in: "-1K", err: true},
+		{in: "10X", err: true},
+		{in: "10i", err: true},
+		{in: "1KiB", e: KB},
+		{in: "1.5Mi", e: 3 * MB / 2},
+	}
+	for _, k := range tests {
+		v, err := ParseBytes(k.in)
+		if k.err {
+			assert.Error(t, err, k.in)
+			continue
+		}
+		assert.NoError(t, err, k.in)
+		assert.Equal(t, k.e, v, k.in)
+	}
+}
+
+func TestParseBytesSI(t *testing.T) {
+	defer SetUnits(GetUnits())
+	SetUnits(Units{SI: true})
+	tests := []struct {
+		in string
+		e  uint64
+	}{
+		{in: "512", e: 512},
+		{in: "2k", e: 2000},
+		{in: "125M", e: 125000000},
+		{in: "1GB", e: 1000000000},
+		{in: "1GiB", e: GB},
+		{in: "2Ti", e: 2 * TB},
+	}
+	for _, k := range tests {
+		v, err := ParseBytes(k.in)
+		assert.NoError(t, err, k.in)
+		assert.Equal(t, k.e, v, k.in)
+	}
+}
+
+func TestUnits(t *testing.T) {
+	iec, si := Units{}, Units{SI: true}
+	iecBits, siBits := Units{Bits: true}, Units{SI: true, Bits: true}
+	tests := []struct {
+		u     Units
+		b     uint64
+		bytes float64
+		unit  string
+		rate  float64
+		runit string
+	}{
+		{iec, 0, 0, "B", 0, "B/s"},
+		{iec, 1023, 1023, "B", 1023, "B/s"},
+		{iec, 1536, 1.5, "KiB", 1.5, "KiB/s"},
+		{iec, 3 * MB, 3, "MiB", 3, "MiB/s"},
+		{iec, 5 * GB, 5, "GiB", 5, "GiB/s"},
+		{iec, 2 * TB, 2, "TiB", 2, "TiB/s"},
+		{iec, 2048 * TB, 2048, "TiB", 2048, "TiB/s"},
+		{si, 999, 999, "B", 999, "B/s"},
+		{si, 1500, 1.5, "kB", 1.5, "kB/s"},
+		{si, 1024, 1.024, "kB", 1.024, "kB/s"},
+		{si, 3000000, 3, "MB", 3, "MB/s"},
+		{si, 5000000000, 5, "GB", 5, "GB/s"},
+		{si, 2000000000000, 2, "TB", 2, "TB/s"},
+		{iecBits, 100, 100, "B", 800, "b/s"},
+		{iecBits, 128, 128, "B", 1, "Kib/s"},
+		{iecBits, 128 * MB, 128, "MiB", 1, "Gib/s"},
+		{siBits, 100, 100, "B", 800, "b/s"},
+		{siBits, 125, 125, "B", 1, "kb/s"},
+		{siBits, 125000000, 125, "MB", 1, "Gb/s"},
+		{siBits, 1250000, 1.25, "MB", 10, "Mb/s"},
+	}
+	for _, k := range tests {
+		v, unit := k.u.Bytes(k.b)
+		assert.InDelta(t, k.bytes, v, 1e-9, "%+v %d", k.u, k.b)
+		assert.Equal(t, k.unit, unit, "%+v %d", k.u, k.b)
+		v, unit = k.u.Rate(k.b)
+		assert.InDelta(t, k.rate, v, 1e-9, "%+v %d/s", k.u, k.b)
+		assert.Equal(t, k.runit, unit, "%+v %d/s", k.u, k.b)
+	}
+}
+
+func TestSetPrefixes(t *testing.T) {
+	var u Units
+	assert.Equal(t, UnitsIEC, u.Prefixes())
+	assert.NoError(t, u.SetPrefixes("SI"))
+	assert.True(t, u.SI)
+	assert.Equal(t, UnitsSI, u.Prefixes())
+	assert.NoError(t, u.SetPrefixes(UnitsIEC))
+	assert.False(t, u.SI)
+	assert.Error(t, u.SetPrefixes("binary"))
+	assert.False(t, u.SI)
+}
+
+func TestConvert(t *testing.T) {
+	defer SetUnits(GetUnits())
+	SetUnits(Units{})
+	v, unit := ConvertBytes(2 * KB)
+	assert.Equal(t, 2.0, v)
+	assert.Equal(t, "KiB", unit)
+	SetUnits(Units{SI: true, Bits: true})
+	v, unit = ConvertBytes(2000)
+	assert.Equal(t, 2.0, v)
+	assert.Equal(t, "kB", unit)
+	v, unit = ConvertRate(2000)
+	assert.Equal(t, 16.0, v)
+	assert.Equal(t, "kb/s", unit)
+	assert.Equal(t, 1000.0, GetUnits().Multiplier())
+}
+
+func TestBytesTo(t *testing.T) {
+	assert.Equal(t, 1.5, BytesToKB(1536))
+	assert.Equal(t, 2.0, BytesToMB(2*MB))
+	assert.Equal(t, 0.5, BytesToGB(GB/2))
+	assert.Equal(t, 3.0, BytesToTB(3*TB))
+	assert.Equal(t, 212, CelsiusToFahrenheit(100))
+}
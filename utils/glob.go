@@ -0,0 +1,49 @@
+package utils
+
+// Glob reports whether s matches the pattern, where * matches any text,
+// including slashes, and ? any single character. Unlike filepath.Match, a
+// pattern like /var/lib/docker/* matches everything under the directory.
+func Glob(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(s); i >= 0; i-- {
+				if Glob(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+			s = s[1:]
+		}
+		pattern = pattern[1:]
+	}
+	return len(s) == 0
+}
+
+// GlobFilter reports whether s passes the patterns: it must not match any
+// pattern that starts with !, and if there are other patterns, it must match
+// one of them. Every string passes an empty filter.
+func GlobFilter(patterns []string, s string) bool {
+	include := false
+	included := false
+	for _, p := range patterns {
+		if len(p) > 0 && p[0] == '!' {
+			if Glob(p[1:], s) {
+				return false
+			}
+			continue
+		}
+		include = true
+		included = included || Glob(p, s)
+	}
+	return !include || included
+}
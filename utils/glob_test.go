@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlob(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "anything", true},
+		{"*", "", true},
+		{"cpu?", "cpu1", true},
+		{"cpu?", "cpu10", false},
+		{"/home*", "/home/user used", true},
+		{"* used", "/var/lib used", true},
+		{"* used", "/var/lib free", false},
+		{"/var/lib/docker/*", "/var/lib/docker/overlay2/x/merged", true},
+		{"main", "main", true},
+		{"main", "mains", false},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, Glob(tc.pattern, tc.s), "%s %s", tc.pattern, tc.s)
+	}
+}
+
+func TestGlobFilter(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		s        string
+		want     bool
+	}{
+		{nil, "/dev/sda1", true},
+		{[]string{"!/dev/loop*"}, "/dev/sda1", true},
+		{[]string{"!/dev/loop*"}, "/dev/loop3", false},
+		{[]string{"/dev/sd*", "/dev/nvme*"}, "/dev/nvme0n1p1", true},
+		{[]string{"/dev/sd*", "/dev/nvme*"}, "/dev/vda", false},
+		{[]string{"/dev/sd*", "!/dev/sdb*"}, "/dev/sdb1", false},
+		{[]string{"/dev/sd*", "!/dev/sdb*"}, "/dev/sda1", true},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, GlobFilter(tc.patterns, tc.s), "%v %s", tc.patterns, tc.s)
+	}
+}
@@ -0,0 +1,123 @@
+package widgets
+
+import (
+	"strings"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+// AlertColor is the border colour of widgets that have firing alerts.
+var AlertColor = ui.ColorRed
+
+// Sampler widgets provide their latest values to the alert rules, and
+// highlight their border while an alert on them is firing. Samples and
+// SetAlert must be called with the widget locked.
+type Sampler interface {
+	// Samples returns the widget's domain, which is its name in layouts, and
+	// the latest value of each of its devices.
+	Samples() (string, map[string]float64)
+	SetAlert(on bool)
+	Lock()
+	Unlock()
+}
+
+func setAlert(b *ui.Block, on bool) {
+	if on {
+		b.BorderStyle = ui.NewStyle(AlertColor, ui.Theme.Block.Border.Bg, ui.ModifierBold)
+		b.TitleStyle = ui.NewStyle(AlertColor, ui.Theme.Block.Title.Bg, ui.ModifierBold)
+	} else {
+		b.BorderStyle, b.TitleStyle = ui.Theme.Block.Border, ui.Theme.Block.Title
+	}
+}
+
+func lastValues(data map[string][]float64) map[string]float64 {
+	vs := make(map[string]float64, len(data))
+	for k, d := range data {
+		if len(d) > 0 {
+			vs[strings.TrimSpace(k)] = d[len(d)-1]
+		}
+	}
+	return vs
+}
+
+// Samples returns the load of each CPU, and the average load as "avg".
+func (cpu *CPUWidget) Samples() (string, map[string]float64) {
+	vs := lastValues(cpu.Data)
+	if avg, ok := vs[AVRG]; ok {
+		delete(vs, AVRG)
+		vs["avg"] = avg
+	} else if len(vs) > 0 {
+		var sum float64
+		for _, v := range vs {
+			sum += v
+		}
+		vs["avg"] = sum / float64(len(vs))
+	}
+	return "cpu", vs
+}
+
+func (cpu *CPUWidget) SetAlert(on bool) { setAlert(cpu.Block, on) }
+
+// Samples returns the used percentage of each kind of memory, e.g. Main.
+func (mem *MemWidget) Samples() (string, map[string]float64) {
+	return "mem", lastValues(mem.Data)
+}
+
+func (mem *MemWidget) SetAlert(on bool) { setAlert(mem.Block, on) }
+
+// Samples returns the charge percentage of each battery.
+func (b *BatteryWidget) Samples() (string, map[string]float64) {
+	return "batt", lastValues(b.Data)
+}
+
+func (b *BatteryWidget) SetAlert(on bool) { setAlert(b.Block, on) }
+
+// Samples returns the charge percentage of all batteries as "total".
+func (b *BatteryGauge) Samples() (string, map[string]float64) {
+	return "power", map[string]float64{"total": float64(b.Percent)}
+}
+
+func (b *BatteryGauge) SetAlert(on bool) { setAlert(&b.Gauge.Gauge.Block, on) }
+
+// Samples returns the temperature of each sensor, in the displayed scale.
+func (temp *TempWidget) Samples() (string, map[string]float64) {
+	vs := make(map[string]float64, len(temp.Data))
+	for k, v := range temp.Data {
+		vs[k] = float64(v)
+	}
+	return "temp", vs
+}
+
+func (temp *TempWidget) SetAlert(on bool) { setAlert(temp.Block, on) }
+
+// Samples returns the bytes received and sent in the last second, as "recv"
+// and "sent", and for local interfaces the packets, errors, and drops, as
+// "recv_packets", "sent_errors", and so on.
+func (net *NetWidget) Samples() (string, map[string]float64) {
+	vs := make(map[string]float64)
+	for i, k := range []string{"recv", "sent"} {
+		if d := net.Lines[i].Data; len(d) > 0 {
+			vs[k] = float64(d[len(d)-1])
+		}
+	}
+	for i, k := range packetMetricNames {
+		if i < len(net.recentPackets) {
+			vs[k] = float64(net.recentPackets[i])
+		}
+	}
+	return "net", vs
+}
+
+func (net *NetWidget) SetAlert(on bool) { setAlert(net.Block, on) }
+
+// Samples returns the used percentage of each partition as "MOUNT used", e.g.
+// "/home used".
+func (disk *DiskWidget) Samples() (string, map[string]float64) {
+	vs := make(map[string]float64, len(disk.Rows))
+	for name, used := range disk.used() {
+		vs[name+" used"] = used
+	}
+	return "disk", vs
+}
+
+func (disk *DiskWidget) SetAlert(on bool) { setAlert(disk.Block, on) }
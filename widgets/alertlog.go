@@ -0,0 +1,51 @@
+package widgets
+
+import (
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+
+	"github.com/xxxserxxx/gotop/v4/alerts"
+)
+
+// AlertLog is a pop-up listing the alerts that fired and resolved, newest
+// first.
+type AlertLog struct {
+	widgets.List
+	engine *alerts.Engine
+	count  int
+}
+
+func NewAlertLog(engine *alerts.Engine) *AlertLog {
+	al := &AlertLog{
+		List:   *widgets.NewList(),
+		engine: engine,
+	}
+	al.Title = tr.Value("widget.label.alertlog")
+	al.SelectedRowStyle = ui.NewStyle(ui.Theme.Default.Fg, ui.ColorClear, ui.ModifierReverse)
+	al.Update()
+	return al
+}
+
+// Update reloads the events, keeping the cursor on the same event.
+func (al *AlertLog) Update() {
+	evs := al.engine.Events()
+	if len(evs) == 0 {
+		al.Rows = []string{tr.Value("alert.none")}
+		al.SelectedRow, al.count = 0, 0
+		return
+	}
+	rows := make([]string, len(evs))
+	for i, ev := range evs {
+		rows[len(evs)-1-i] = ev.Time.Format("2006-01-02 15:04:05") + "  " + ev.Message
+	}
+	if al.count > 0 {
+		al.SelectedRow += len(evs) - al.count
+	}
+	al.Rows, al.count = rows, len(evs)
+}
+
+func (al *AlertLog) Resize(termWidth, termHeight int) {
+	w, h := termWidth*4/5, termHeight*4/5
+	x, y := (termWidth-w)/2, (termHeight-h)/2
+	al.List.SetRect(x, y, w+x, h+y)
+}
@@ -10,22 +10,33 @@ import (
 	"github.com/VictoriaMetrics/metrics"
 	"github.com/distatus/battery"
 
+	"github.com/xxxserxxx/gotop/v4/devices"
 	ui "github.com/xxxserxxx/gotop/v4/termui"
 )
 
 type BatteryWidget struct {
 	*ui.LineGraph
 	updateInterval time.Duration
+	host           string
+	critical       hysteresis
 }
 
-func NewBatteryWidget(horizontalScale int) *BatteryWidget {
+// NewBatteryWidget creates a battery charge graph for host, which is either
+// the name of a remote or devices.Local.
+func NewBatteryWidget(horizontalScale int, host string) *BatteryWidget {
 	self := &BatteryWidget{
 		LineGraph:      ui.NewLineGraph(),
 		updateInterval: time.Minute,
+		host:           host,
+		critical:       make(hysteresis),
 	}
 	self.Title = tr.Value("widget.label.battery")
 	self.HorizontalScale = horizontalScale
 
+	if passive {
+		return self
+	}
+
 	// intentional duplicate
 	// adds 2 datapoints to the graph, otherwise the dot is difficult to see
 	self.update()
@@ -50,7 +61,7 @@ func (b *BatteryWidget) EnableMetric() {
 	}
 	for i, _ := range bats {
 		id := makeID(i)
-		metrics.NewGauge(makeName("battery", i), func() float64 {
+		metrics.GetOrCreateGauge(makeName("battery", i), func() float64 {
 			if ds, ok := b.Data[id]; ok {
 				return ds[len(ds)-1]
 			}
@@ -68,6 +79,18 @@ func (b *BatteryWidget) Scale(i int) {
 }
 
 func (b *BatteryWidget) update() {
+	if b.host != devices.Local {
+		for k, percentFull := range devices.RemoteBatteries(b.host) {
+			i, err := strconv.Atoi(k)
+			if err != nil { // the total
+				continue
+			}
+			id := makeID(i)
+			b.Data[id] = append(b.Data[id], percentFull)
+			b.Labels[id] = fmt.Sprintf("%3.0f%%", percentFull)
+		}
+		return
+	}
 	batteries, err := battery.GetAll()
 	if err != nil {
 		switch errt := err.(type) {
@@ -9,17 +9,26 @@ import (
 	"github.com/VictoriaMetrics/metrics"
 	"github.com/distatus/battery"
 
+	"github.com/xxxserxxx/gotop/v4/devices"
 	"github.com/xxxserxxx/gotop/v4/termui"
 )
 
 type BatteryGauge struct {
 	*termui.Gauge
+	host     string
+	critical hysteresis
 }
 
-func NewBatteryGauge() *BatteryGauge {
-	self := &BatteryGauge{Gauge: termui.NewGauge()}
+// NewBatteryGauge creates a battery bar for host, which is either the name of
+// a remote or devices.Local.
+func NewBatteryGauge(host string) *BatteryGauge {
+	self := &BatteryGauge{Gauge: termui.NewGauge(), host: host, critical: make(hysteresis)}
 	self.Title = tr.Value("widget.label.gauge")
 
+	if passive {
+		return self
+	}
+
 	self.update()
 
 	go func() {
@@ -34,7 +43,7 @@ func NewBatteryGauge() *BatteryGauge {
 }
 
 func (b *BatteryGauge) EnableMetric() {
-	metrics.NewGauge(makeName("battery", "total"), func() float64 {
+	metrics.GetOrCreateGauge(makeName("battery", "total"), func() float64 {
 		return float64(b.Percent)
 	})
 }
@@ -43,6 +52,16 @@ func (b *BatteryGauge) EnableMetric() {
 var errLogged = false
 
 func (b *BatteryGauge) update() {
+	if b.host != devices.Local {
+		total, ok := devices.RemoteBatteries(b.host)["total"]
+		if !ok {
+			b.Label = fmt.Sprintf("N/A")
+			return
+		}
+		b.Percent = int(total)
+		b.Label = fmt.Sprintf("%d%%", b.Percent)
+		return
+	}
 	bats, err := battery.GetAll()
 	if err != nil {
 		if !errLogged {
@@ -0,0 +1,353 @@
+package widgets
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	tui "github.com/gizak/termui/v3"
+
+	"github.com/xxxserxxx/gotop/v4/devices"
+	ui "github.com/xxxserxxx/gotop/v4/termui"
+	"github.com/xxxserxxx/gotop/v4/utils"
+)
+
+// Columns of the connections table; these are also the sort methods
+const (
+	ConnSortProto = iota
+	ConnSortLocal
+	ConnSortRemote
+	ConnSortState
+	ConnSortPid
+	ConnSortProcess
+)
+
+// connSortKeys are the keys that sort the connections by each column.
+var connSortKeys = map[string]int{
+	"P": ConnSortProto,
+	"L": ConnSortLocal,
+	"r": ConnSortRemote,
+	"s": ConnSortState,
+	"p": ConnSortPid,
+	"n": ConnSortProcess,
+}
+
+// connSummaryStates are always in the summary, and exported as metrics; other
+// states are only summarized if there are connections in them.
+var connSummaryStates = []string{"ESTABLISHED", "TIME_WAIT", "LISTEN"}
+
+// ConnsWidget lists the local TCP and UDP sockets, with the processes that own
+// them, and summarizes how many are in each state.
+type ConnsWidget struct {
+	*ui.Table
+	entry          *ui.Entry
+	updateInterval time.Duration
+	sortMethod     int
+	filter         string
+	all            []devices.Conn
+	// counts are the connections in each state. It's replaced, rather than
+	// changed, by updates, because the metrics read it.
+	counts      map[string]int
+	previousKey string
+}
+
+// NewConnsWidget creates a connections table for host. Remotes don't export
+// their connections, so for them the widget only says so.
+func NewConnsWidget(host string) *ConnsWidget {
+	self := &ConnsWidget{
+		Table:          ui.NewTable(),
+		updateInterval: time.Second,
+		sortMethod:     ConnSortProto,
+		counts:         make(map[string]int),
+	}
+	self.Table.Tr = tr
+	self.entry = &ui.Entry{
+		Style: self.TitleStyle,
+		Label: tr.Value("widget.proc.filter"),
+		UpdateCallback: func(val string) {
+			self.filter = val
+			self.render()
+		},
+	}
+	self.Title = tr.Value("widget.label.conns")
+	self.ShowCursor = true
+	self.ShowLocation = true
+	self.ColGap = 2
+	self.UniqueCol = ConnSortLocal
+	self.ColResizer = func() {
+		// Addresses are at most as wide as [IPv6]:port
+		addr := utils.MaxInt(10, (self.Inner.Dx()-45)/2)
+		if addr > 47 {
+			addr = 47
+		}
+		self.ColWidths = []int{5, addr, addr, 11, 7, utils.MaxInt(12, self.Inner.Dx()-2*addr-33)}
+	}
+	self.render()
+
+	if host != devices.Local {
+		self.Title = tr.Value("widget.conns.remote", host)
+		return self
+	}
+	if passive {
+		return self
+	}
+
+	self.update()
+
+	go func() {
+		for range time.NewTicker(self.updateInterval).C {
+			self.Lock()
+			self.update()
+			self.Unlock()
+		}
+	}()
+
+	return self
+}
+
+// EnableMetric exports the number of connections, and of those that are
+// established, waiting to close, or listening.
+func (c *ConnsWidget) EnableMetric() {
+	metrics.GetOrCreateGauge(makeName("conns", "total"), func() float64 {
+		return float64(len(c.all))
+	})
+	for _, state := range connSummaryStates {
+		s := state
+		metrics.GetOrCreateGauge(makeName("conns", strings.ToLower(s)), func() float64 {
+			return float64(c.counts[s])
+		})
+	}
+}
+
+func (c *ConnsWidget) update() {
+	conns, err := devices.Connections()
+	if err != nil {
+		log.Println(tr.Value("widget.conns.err", err.Error()))
+		return
+	}
+	c.setConns(conns)
+}
+
+// setConns replaces the connections, and counts, filters and sorts them.
+func (c *ConnsWidget) setConns(conns []devices.Conn) {
+	c.all = conns
+	counts := make(map[string]int)
+	for _, conn := range conns {
+		counts[conn.State]++
+	}
+	c.counts = counts
+	c.render()
+}
+
+// ChangeSortMethod sorts the table by one of the ConnSort* columns.
+func (c *ConnsWidget) ChangeSortMethod(method int) {
+	if method < ConnSortProto || method > ConnSortProcess || method == c.sortMethod {
+		return
+	}
+	c.sortMethod = method
+	c.ScrollTop()
+	c.render()
+}
+
+// HandleClick sorts by the column if the header was clicked, and otherwise
+// moves the cursor to the clicked row.
+func (c *ConnsWidget) HandleClick(x, y int) {
+	if !(x >= c.Inner.Min.X && x < c.Inner.Max.X && y == c.Inner.Min.Y) {
+		c.Table.HandleClick(x, y)
+		return
+	}
+	cur := c.Inner.Min.X + c.PadLeft
+	for i, w := range c.ColWidths {
+		if x < cur+w+c.ColGap {
+			c.ChangeSortMethod(i)
+			return
+		}
+		cur += w + c.ColGap
+	}
+}
+
+// HandleEvent handles the filter entry while it's being edited, and
+// otherwise navigation and sorting.
+func (c *ConnsWidget) HandleEvent(e tui.Event) bool {
+	if c.entry.HandleEvent(e) {
+		return true
+	}
+	previous := sequence(&c.previousKey, e.ID)
+	if e.ID == "<MouseLeft>" {
+		m := e.Payload.(tui.Mouse)
+		c.HandleClick(m.X, m.Y)
+		return true
+	}
+	if navigate(c.Table, e, previous) {
+		return true
+	}
+	if method, ok := connSortKeys[e.ID]; ok {
+		c.ChangeSortMethod(method)
+		return true
+	}
+	if e.ID == "/" {
+		c.entry.SetEditing(true)
+		return true
+	}
+	return false
+}
+
+func (c *ConnsWidget) SetRect(x1, y1, x2, y2 int) {
+	c.Table.SetRect(x1, y1, x2, y2)
+	c.entry.SetRect(x1+2, y2-1, x2-2, y2)
+}
+
+// Draw draws the table, with the summary of the states and the filter in the
+// bottom border.
+func (c *ConnsWidget) Draw(buf *tui.Buffer) {
+	c.Table.Draw(buf)
+	if summary := c.summary(); summary != "" && len(summary)+4 <= c.Dx() {
+		buf.SetString(summary, c.TitleStyle, image.Pt(c.Max.X-len(summary)-2, c.Max.Y-1))
+	}
+	c.entry.Draw(buf)
+}
+
+// summary counts the connections in each state, e.g. " 12 ESTABLISHED  3
+// TIME_WAIT  5 LISTEN ".
+func (c *ConnsWidget) summary() string {
+	if len(c.all) == 0 {
+		return ""
+	}
+	states := append([]string(nil), connSummaryStates...)
+	var others []string
+	for state := range c.counts {
+		summarized := false
+		for _, s := range connSummaryStates {
+			summarized = summarized || s == state
+		}
+		if !summarized {
+			others = append(others, state)
+		}
+	}
+	sort.Strings(others)
+	var b strings.Builder
+	for _, state := range append(states, others...) {
+		fmt.Fprintf(&b, " %d %s ", c.counts[state], state)
+	}
+	return b.String()
+}
+
+func (c *ConnsWidget) filterConns() []devices.Conn {
+	if c.filter == "" {
+		return append([]devices.Conn(nil), c.all...)
+	}
+	var filtered []devices.Conn
+	for _, conn := range c.all {
+		for _, f := range connRow(conn) {
+			if strings.Contains(f, c.filter) {
+				filtered = append(filtered, conn)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// render filters and sorts the connections into the table.
+func (c *ConnsWidget) render() {
+	c.Header = []string{
+		tr.Value("widget.conns.proto"),
+		tr.Value("widget.conns.localaddr"),
+		tr.Value("widget.conns.remoteaddr"),
+		tr.Value("widget.conns.state"),
+		tr.Value("widget.conns.pid"),
+		tr.Value("widget.conns.process"),
+	}
+	c.Header[c.sortMethod] += _downArrow
+
+	conns := c.filterConns()
+	sort.SliceStable(conns, func(i, j int) bool {
+		a, b := conns[i], conns[j]
+		switch c.sortMethod {
+		case ConnSortLocal:
+			return lessAddr(a.LocalAddr, a.LocalPort, b.LocalAddr, b.LocalPort)
+		case ConnSortRemote:
+			return lessAddr(a.RemoteAddr, a.RemotePort, b.RemoteAddr, b.RemotePort)
+		case ConnSortState:
+			return a.State < b.State
+		case ConnSortPid:
+			return a.Pid < b.Pid
+		case ConnSortProcess:
+			return strings.ToLower(a.Process) < strings.ToLower(b.Process)
+		default:
+			if a.Proto != b.Proto {
+				return a.Proto < b.Proto
+			}
+			return lessAddr(a.LocalAddr, a.LocalPort, b.LocalAddr, b.LocalPort)
+		}
+	})
+
+	c.Rows = make([][]string, len(conns))
+	for i, conn := range conns {
+		c.Rows[i] = connRow(conn)
+	}
+}
+
+// lessAddr orders addresses numerically, and then by port.
+func lessAddr(a net.IP, ap int, b net.IP, bp int) bool {
+	if cmp := strings.Compare(string(a.To16()), string(b.To16())); cmp != 0 {
+		return cmp < 0
+	}
+	return ap < bp
+}
+
+// connRow is the table row of a connection; processes gotop can't see are
+// shown as "-".
+func connRow(conn devices.Conn) []string {
+	pid, process := "-", "-"
+	if conn.Pid > 0 {
+		pid, process = strconv.Itoa(conn.Pid), conn.Process
+	}
+	return []string{conn.Proto, conn.Local(), conn.Remote(), conn.State, pid, process}
+}
+
+// rowConn is the connection of a table row, as sent by a daemon.
+func rowConn(row []string) (devices.Conn, bool) {
+	if len(row) < 6 {
+		return devices.Conn{}, false
+	}
+	conn := devices.Conn{Proto: row[0], State: row[3]}
+	for i, addr := range row[1:3] {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return conn, false
+		}
+		p, _ := strconv.Atoi(port)
+		if i == 0 {
+			conn.LocalAddr, conn.LocalPort = net.ParseIP(host), p
+		} else {
+			conn.RemoteAddr, conn.RemotePort = net.ParseIP(host), p
+		}
+	}
+	if pid, err := strconv.Atoi(row[4]); err == nil {
+		conn.Pid, conn.Process = pid, row[5]
+	}
+	return conn, true
+}
+
+// Details describes the connection under the cursor.
+func (c *ConnsWidget) Details() (string, []string, bool) {
+	if c.SelectedRow < 0 || c.SelectedRow >= len(c.Rows) {
+		return "", nil, false
+	}
+	row := c.Rows[c.SelectedRow]
+	var lines []string
+	for i, h := range c.Header {
+		if i < len(row) {
+			h = strings.TrimSuffix(h, _downArrow)
+			lines = append(lines, fmt.Sprintf("%-14s %s", h+":", row[i]))
+		}
+	}
+	return row[ConnSortLocal], lines, true
+}
@@ -18,6 +18,7 @@ type CPUWidget struct {
 	CPUCount        int
 	ShowAverageLoad bool
 	ShowPerCPULoad  bool
+	host            string
 	updateInterval  time.Duration
 	cpuLoads        map[string]float64
 	average         ewma.MovingAverage
@@ -25,10 +26,13 @@ type CPUWidget struct {
 
 var cpuLabels []string
 
-func NewCPUWidget(updateInterval time.Duration, horizontalScale int, showAverageLoad bool, showPerCPULoad bool) *CPUWidget {
+// NewCPUWidget creates a CPU load graph for host, which is either the name of
+// a remote or devices.Local.
+func NewCPUWidget(updateInterval time.Duration, horizontalScale int, showAverageLoad bool, showPerCPULoad bool, host string) *CPUWidget {
 	self := &CPUWidget{
 		LineGraph:       ui.NewLineGraph(),
 		CPUCount:        len(cpuLabels),
+		host:            host,
 		updateInterval:  updateInterval,
 		ShowAverageLoad: showAverageLoad,
 		ShowPerCPULoad:  showPerCPULoad,
@@ -53,12 +57,16 @@ func NewCPUWidget(updateInterval time.Duration, horizontalScale int, showAverage
 
 	if self.ShowPerCPULoad {
 		cpus := make(map[string]int)
-		devices.UpdateCPU(cpus, self.updateInterval, self.ShowPerCPULoad)
+		devices.HostCPU(self.host, cpus, self.updateInterval, self.ShowPerCPULoad)
 		for k, v := range cpus {
 			self.Data[k] = []float64{float64(v)}
 		}
 	}
 
+	if passive {
+		return self
+	}
+
 	self.update()
 
 	go func() {
@@ -74,7 +82,7 @@ const AVRG = "AVRG"
 
 func (cpu *CPUWidget) EnableMetric() {
 	if cpu.ShowAverageLoad {
-		metrics.NewGauge(makeName("cpu", " avg"), func() float64 {
+		metrics.GetOrCreateGauge(makeName("cpu", "avg"), func() float64 {
 			return cpu.cpuLoads[AVRG]
 		})
 	} else {
@@ -83,7 +91,7 @@ func (cpu *CPUWidget) EnableMetric() {
 		for key, perc := range cpus {
 			kc := key
 			cpu.cpuLoads[key] = float64(perc)
-			metrics.NewGauge(makeName("cpu", key), func() float64 {
+			metrics.GetOrCreateGauge(makeName("cpu", key), func() float64 {
 				return cpu.cpuLoads[kc]
 			})
 		}
@@ -97,7 +105,7 @@ func (cpu *CPUWidget) Scale(i int) {
 func (cpu *CPUWidget) update() {
 	go func() {
 		cpus := make(map[string]int)
-		devices.UpdateCPU(cpus, cpu.updateInterval, true)
+		devices.HostCPU(cpu.host, cpus, cpu.updateInterval, true)
 		cpu.Lock()
 		defer cpu.Unlock()
 		// AVG = ((AVG*i)+n)/(i+1)
@@ -0,0 +1,119 @@
+package widgets
+
+import (
+	ui "github.com/gizak/termui/v3"
+)
+
+// Thresholds of the critical conditions, and how far back past them values
+// must go before the condition can trigger again
+const (
+	memCritical        = 95
+	memCriticalMargin  = 5
+	diskCritical       = 98
+	diskCriticalMargin = 3
+	battCritical       = 10
+	battCriticalMargin = 5
+	// Below a sensor's own critical temperature, in Celsius
+	tempCriticalMargin = 5
+)
+
+// Critical widgets report when one of their devices crosses into a critical
+// state: memory or a disk nearly full, a battery nearly empty, or a sensor
+// over its critical temperature. Critical and Invert must be called with the
+// widget locked.
+type Critical interface {
+	// Critical returns true if a device became critical since the last call.
+	Critical() bool
+	// Invert draws the widget's border in reverse video, or stops doing so.
+	Invert(on bool)
+	Lock()
+	Unlock()
+}
+
+// hysteresis tracks which devices are in a critical state. A device becomes
+// critical when its value reaches the threshold, and stops being critical
+// once it's back below the threshold by the margin, so a value hovering
+// around the threshold doesn't trigger every update.
+type hysteresis map[string]bool
+
+// update records the device's value, and returns true if it just became
+// critical. For thresholds that are minimums, such as battery charge, pass
+// the negated value and threshold.
+func (h hysteresis) update(key string, v, threshold, margin float64) bool {
+	if h[key] {
+		if v < threshold-margin {
+			delete(h, key)
+		}
+		return false
+	}
+	if v >= threshold {
+		h[key] = true
+		return true
+	}
+	return false
+}
+
+func invert(b *ui.Block, on bool) {
+	if on {
+		b.BorderStyle.Modifier |= ui.ModifierReverse
+		b.TitleStyle.Modifier |= ui.ModifierReverse
+	} else {
+		b.BorderStyle.Modifier &^= ui.ModifierReverse
+		b.TitleStyle.Modifier &^= ui.ModifierReverse
+	}
+}
+
+// Critical returns true when main memory use reaches 95%.
+func (mem *MemWidget) Critical() bool {
+	d := mem.Data["Main"]
+	return len(d) > 0 && mem.critical.update("Main", d[len(d)-1], memCritical, memCriticalMargin)
+}
+
+func (mem *MemWidget) Invert(on bool) { invert(mem.Block, on) }
+
+// Critical returns true when a partition is 98% full.
+func (disk *DiskWidget) Critical() bool {
+	crossed := false
+	for name, used := range disk.used() {
+		crossed = disk.critical.update(name, used, diskCritical, diskCriticalMargin) || crossed
+	}
+	return crossed
+}
+
+func (disk *DiskWidget) Invert(on bool) { invert(disk.Block, on) }
+
+// Critical returns true when a sensor reaches its critical temperature, for
+// sensors that report one.
+func (temp *TempWidget) Critical() bool {
+	margin := float64(tempCriticalMargin)
+	if temp.TempScale == Fahrenheit {
+		margin = margin * 9 / 5
+	}
+	crossed := false
+	for name, crit := range temp.Criticals {
+		if v, ok := temp.Data[name]; ok {
+			crossed = temp.critical.update(name, float64(v), float64(crit), margin) || crossed
+		}
+	}
+	return crossed
+}
+
+func (temp *TempWidget) Invert(on bool) { invert(temp.Block, on) }
+
+// Critical returns true when a battery's charge falls to 10%.
+func (b *BatteryWidget) Critical() bool {
+	crossed := false
+	for name, v := range lastValues(b.Data) {
+		crossed = b.critical.update(name, -v, -battCritical, battCriticalMargin) || crossed
+	}
+	return crossed
+}
+
+func (b *BatteryWidget) Invert(on bool) { invert(b.Block, on) }
+
+// Critical returns true when the total charge falls to 10%.
+func (b *BatteryGauge) Critical() bool {
+	return b.critical.update("total", -float64(b.Percent), -battCritical, battCriticalMargin)
+}
+
+func (b *BatteryGauge) Invert(on bool) { invert(&b.Gauge.Gauge.Block, on) }
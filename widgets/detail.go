@@ -0,0 +1,73 @@
+package widgets
+
+import (
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// Detailed widgets can describe the item under their cursor.
+type Detailed interface {
+	// Details returns a title and lines describing the selected item, or
+	// false if nothing is selected. It must be called with the widget locked.
+	Details() (string, []string, bool)
+	Lock()
+	Unlock()
+}
+
+// DetailView is a pop-up showing the details of a widget's selected item.
+type DetailView struct {
+	widgets.List
+	source Detailed
+}
+
+func NewDetailView() *DetailView {
+	dv := &DetailView{
+		List: *widgets.NewList(),
+	}
+	dv.SelectedRowStyle = ui.NewStyle(ui.Theme.Default.Fg, ui.ColorClear, ui.ModifierReverse)
+	return dv
+}
+
+// Show displays the details of the widget's selected item, returning false if
+// it has none.
+func (dv *DetailView) Show(d Detailed) bool {
+	dv.source = d
+	dv.SelectedRow = 0
+	return dv.Update()
+}
+
+// Update reloads the details, which change as the widget updates.
+func (dv *DetailView) Update() bool {
+	if dv.source == nil {
+		return false
+	}
+	dv.source.Lock()
+	title, lines, ok := dv.source.Details()
+	dv.source.Unlock()
+	if !ok {
+		return false
+	}
+	dv.Title = " " + title + " "
+	dv.Rows = lines
+	return true
+}
+
+func (dv *DetailView) Resize(termWidth, termHeight int) {
+	textWidth := 40
+	for _, r := range dv.Rows {
+		if textWidth < len([]rune(r))+4 {
+			textWidth = len([]rune(r)) + 4
+		}
+	}
+	if textWidth > termWidth {
+		textWidth = termWidth
+	}
+	textHeight := len(dv.Rows) + 2
+	if textHeight > termHeight {
+		textHeight = termHeight
+	}
+	x := (termWidth - textWidth) / 2
+	y := (termHeight - textHeight) / 2
+
+	dv.List.SetRect(x, y, textWidth+x, textHeight+y)
+}
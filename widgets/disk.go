@@ -2,14 +2,20 @@ package widgets
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/VictoriaMetrics/metrics"
+	tui "github.com/gizak/termui/v3"
 	psDisk "github.com/shirou/gopsutil/v3/disk"
 
+	"github.com/xxxserxxx/gotop/v4/devices"
 	ui "github.com/xxxserxxx/gotop/v4/termui"
 	"github.com/xxxserxxx/gotop/v4/utils"
 )
@@ -23,30 +29,87 @@ type Partition struct {
 	BytesWrittenRecently string
 	UsedPercent          uint32
 	Free                 string
+	// The details are only known for local partitions
+	Fstype      string
+	Opts        []string
+	Total       uint64
+	Used        uint64
+	FreeBytes   uint64
+	InodesTotal uint64
+	InodesUsed  uint64
+	// BlockDevice is the disk the partition is on, or for device mapper
+	// devices, the devices it's mapped onto
+	BlockDevice string
 }
 
 type DiskWidget struct {
 	*ui.Table
 	updateInterval time.Duration
 	Partitions     map[string]*Partition
+	// Mounts are glob patterns of the mountpoints to show, or with a !, to
+	// hide; all are shown if it's empty
+	Mounts   []string
+	host     string
+	critical hysteresis
+	// keys are the Partitions keys of the rows
+	keys        []string
+	previousKey string
+	// columns are the optional columns shown after Free
+	columns []string
+	// Rows where the space or inodes used reach Warn percent are drawn in
+	// WarnColor; 0 turns this off.
+	Warn      int
+	WarnColor tui.Color
 }
 
-func NewDiskWidget() *DiskWidget {
+// The optional columns of the disk widget
+const (
+	DiskInodes = "inodes"
+	DiskFstype = "fstype"
+	DiskTotal  = "total"
+)
+
+// DiskColumns are the optional columns, and their widths.
+var DiskColumns = map[string]int{DiskInodes: 5, DiskFstype: 6, DiskTotal: 6}
+
+// NewDiskWidget creates a partition table for host, which is either the name
+// of a remote or devices.Local. Remotes only report the used percentage. The
+// mounts filter only applies to the local host.
+func NewDiskWidget(mounts []string, host string) *DiskWidget {
 	self := &DiskWidget{
 		Table:          ui.NewTable(),
 		updateInterval: time.Second,
 		Partitions:     make(map[string]*Partition),
+		Mounts:         mounts,
+		host:           host,
+		critical:       make(hysteresis),
 	}
 	self.Table.Tr = tr
 	self.Title = tr.Value("widget.label.disk")
-	self.Header = []string{tr.Value("widget.disk.disk"), tr.Value("widget.disk.mount"), tr.Value("widget.disk.used"), tr.Value("widget.disk.free"), tr.Value("widget.disk.rs"), tr.Value("widget.disk.ws")}
 	self.ColGap = 2
+	self.ShowCursor = true
+	self.UniqueCol = 0
+	self.WarnColor = tui.ColorRed
+	self.SetColumns(nil)
 	self.ColResizer = func() {
-		self.ColWidths = []int{
-			utils.MaxInt(4, (self.Inner.Dx()-29)/2),
-			utils.MaxInt(5, (self.Inner.Dx()-29)/2),
-			4, 5, 5, 5,
+		widths := []int{4, 6}
+		for _, c := range self.columns {
+			widths = append(widths, DiskColumns[c])
 		}
+		widths = append(widths, 6, 6)
+		// the device and mountpoint share the space the others leave
+		fixed := self.ColGap * (len(widths) + 1)
+		for _, w := range widths {
+			fixed += w
+		}
+		self.ColWidths = append([]int{
+			utils.MaxInt(4, (self.Inner.Dx()-fixed)/2),
+			utils.MaxInt(5, (self.Inner.Dx()-fixed)/2),
+		}, widths...)
+	}
+
+	if passive {
+		return self
 	}
 
 	self.update()
@@ -65,55 +128,50 @@ func NewDiskWidget() *DiskWidget {
 func (disk *DiskWidget) EnableMetric() {
 	for key, part := range disk.Partitions {
 		pc := part
-		metrics.NewGauge(makeName("disk", strings.ReplaceAll(key, "/", ":")), func() float64 {
+		metrics.GetOrCreateGauge(makeName("disk", strings.ReplaceAll(key, "/", ":")), func() float64 {
 			return float64(pc.UsedPercent) / 100.0
 		})
 	}
 }
 
 func (disk *DiskWidget) update() {
-	partitions, err := psDisk.Partitions(false)
+	if disk.host != devices.Local {
+		disk.updateRemote()
+		disk.renderRows()
+		return
+	}
+	partitions, _, err := devices.Partitions()
 	if err != nil {
 		log.Printf(tr.Value("error.setup", "disk-partitions", err.Error()))
 		return
 	}
 
 	// add partition if it's new
+	seen := make(map[string]bool)
 	for _, partition := range partitions {
-		// don't show loop devices
-		if strings.HasPrefix(partition.Device, "/dev/loop") {
-			continue
-		}
-		// don't show docker container filesystems
-		if strings.HasPrefix(partition.Mountpoint, "/var/lib/docker/") {
+		if !disk.wanted(partition.Mountpoint) {
 			continue
 		}
+		key := partitionKey(partition)
+		seen[key] = true
 		// check if partition doesn't already exist in our list
-		if _, ok := disk.Partitions[partition.Device]; !ok {
-			disk.Partitions[partition.Device] = &Partition{
-				Device:     partition.Device,
-				MountPoint: partition.Mountpoint,
+		if _, ok := disk.Partitions[key]; !ok {
+			disk.Partitions[key] = &Partition{
+				Device:      partition.Device,
+				MountPoint:  partition.Mountpoint,
+				Fstype:      partition.Fstype,
+				Opts:        partition.Opts,
+				BlockDevice: blockDevice(partition.Device),
 			}
 		}
 	}
 
 	// delete a partition if it no longer exists
-	toDelete := []string{}
-	for device := range disk.Partitions {
-		exists := false
-		for _, partition := range partitions {
-			if device == partition.Device {
-				exists = true
-				break
-			}
-		}
-		if !exists {
-			toDelete = append(toDelete, device)
+	for key := range disk.Partitions {
+		if !seen[key] {
+			delete(disk.Partitions, key)
 		}
 	}
-	for _, device := range toDelete {
-		delete(disk.Partitions, device)
-	}
 
 	// updates partition info. We add 0.5 to all values to make sure the truncation rounds
 	for _, partition := range disk.Partitions {
@@ -123,9 +181,16 @@ func (disk *DiskWidget) update() {
 			continue
 		}
 		partition.UsedPercent = uint32(usage.UsedPercent + 0.5)
+		partition.Total, partition.Used, partition.FreeBytes = usage.Total, usage.Used, usage.Free
+		partition.InodesTotal, partition.InodesUsed = usage.InodesTotal, usage.InodesUsed
 		bytesFree, magnitudeFree := utils.ConvertBytes(usage.Free)
 		partition.Free = fmt.Sprintf("%3d%s", uint64(bytesFree+0.5), magnitudeFree)
 
+		// pseudo filesystems have no IO counters
+		if !strings.HasPrefix(partition.Device, "/") {
+			partition.BytesReadRecently, partition.BytesWrittenRecently = "-", "-"
+			continue
+		}
 		ioCounters, err := psDisk.IOCounters(partition.Device)
 		if err != nil {
 			log.Printf(tr.Value("error.recovfetch", "partition-"+partition.Device+"-rw", err.Error()))
@@ -149,8 +214,54 @@ func (disk *DiskWidget) update() {
 		partition.BytesRead, partition.BytesWritten = bytesRead, bytesWritten
 	}
 
-	// converts self.Partitions into self.Rows which is a [][]String
+	disk.renderRows()
+}
+
+// SetColumns shows the optional columns, which are DiskInodes, DiskFstype, and
+// DiskTotal, after the free space. Unknown columns are ignored.
+func (disk *DiskWidget) SetColumns(columns []string) {
+	disk.columns = disk.columns[:0]
+	for _, c := range columns {
+		if _, ok := DiskColumns[c]; ok {
+			disk.columns = append(disk.columns, c)
+		}
+	}
+	disk.Header = []string{tr.Value("widget.disk.disk"), tr.Value("widget.disk.mount"), tr.Value("widget.disk.used"), tr.Value("widget.disk.free")}
+	for _, c := range disk.columns {
+		disk.Header = append(disk.Header, tr.Value("widget.disk.column."+c))
+	}
+	disk.Header = append(disk.Header, tr.Value("widget.disk.rs"), tr.Value("widget.disk.ws"))
+	disk.renderRows()
+}
+
+// partitionKey identifies a partition by its device, or for pseudo
+// filesystems like tmpfs, which share a device name, by device and mountpoint.
+func partitionKey(p psDisk.PartitionStat) string {
+	if strings.HasPrefix(p.Device, "/") {
+		return p.Device
+	}
+	return p.Device + ":" + p.Mountpoint
+}
+
+// wanted reports whether the mountpoint passes the Mounts filter, whose
+// patterns are globs as for the disk filters, including ! exclusions.
+func (disk *DiskWidget) wanted(mountpoint string) bool {
+	return utils.GlobFilter(disk.Mounts, mountpoint)
+}
 
+// updateRemote replaces the partitions with those reported by the remote host.
+func (disk *DiskWidget) updateRemote() {
+	disk.Partitions = make(map[string]*Partition)
+	for device, used := range devices.RemoteDisks(disk.host) {
+		disk.Partitions[device] = &Partition{
+			Device:      device,
+			UsedPercent: uint32(used*100 + 0.5),
+		}
+	}
+}
+
+// renderRows converts disk.Partitions into disk.Rows, which is a [][]string
+func (disk *DiskWidget) renderRows() {
 	sortedPartitions := []string{}
 	for seriesName := range disk.Partitions {
 		sortedPartitions = append(sortedPartitions, seriesName)
@@ -158,15 +269,157 @@ func (disk *DiskWidget) update() {
 	sort.Strings(sortedPartitions)
 
 	disk.Rows = make([][]string, len(disk.Partitions))
+	disk.keys = sortedPartitions
 
 	for i, key := range sortedPartitions {
 		partition := disk.Partitions[key]
-		disk.Rows[i] = make([]string, 6)
-		disk.Rows[i][0] = strings.Replace(strings.Replace(partition.Device, "/dev/", "", -1), "mapper/", "", -1)
-		disk.Rows[i][1] = partition.MountPoint
-		disk.Rows[i][2] = fmt.Sprintf("%d%%", partition.UsedPercent)
-		disk.Rows[i][3] = partition.Free
-		disk.Rows[i][4] = partition.BytesReadRecently
-		disk.Rows[i][5] = partition.BytesWrittenRecently
+		row := []string{
+			strings.Replace(strings.Replace(partition.Device, "/dev/", "", -1), "mapper/", "", -1),
+			partition.MountPoint,
+			fmt.Sprintf("%d%%", partition.UsedPercent),
+			partition.Free,
+		}
+		for _, c := range disk.columns {
+			row = append(row, partition.column(c))
+		}
+		disk.Rows[i] = append(row, partition.BytesReadRecently, partition.BytesWrittenRecently)
+	}
+	disk.colorRows()
+}
+
+// column returns the value of one of the optional columns; values that aren't
+// known, such as those of remote partitions, are shown as -.
+func (p *Partition) column(c string) string {
+	switch {
+	case c == DiskInodes && p.InodesTotal > 0:
+		return fmt.Sprintf("%d%%", uint64(float64(p.InodesUsed)/float64(p.InodesTotal)*100+0.5))
+	case c == DiskFstype && p.Fstype != "":
+		return p.Fstype
+	case c == DiskTotal && p.Total > 0:
+		total, unit := utils.ConvertBytes(p.Total)
+		return fmt.Sprintf("%3d%s", uint64(total+0.5), unit)
+	}
+	return "-"
+}
+
+// colorRows colours the rows whose used space or inodes reach the warning
+// threshold. It's read from the rows so that it also works for data from a
+// daemon.
+func (disk *DiskWidget) colorRows() {
+	disk.RowColors = make(map[int]tui.Color)
+	if disk.Warn <= 0 {
+		return
+	}
+	cols := []int{2}
+	for i, c := range disk.columns {
+		if c == DiskInodes {
+			cols = append(cols, 4+i)
+		}
+	}
+	for i, r := range disk.Rows {
+		for _, c := range cols {
+			if c >= len(r) {
+				continue
+			}
+			if v, err := strconv.Atoi(strings.TrimSuffix(r[c], "%")); err == nil && v >= disk.Warn {
+				disk.RowColors[i] = disk.WarnColor
+			}
+		}
+	}
+}
+
+// used returns the used percentage of each partition, by mount point, or
+// device for partitions without one. It's read from the rows so that it also
+// works for data from a daemon.
+func (disk *DiskWidget) used() map[string]float64 {
+	vs := make(map[string]float64, len(disk.Rows))
+	for _, r := range disk.Rows {
+		if len(r) < 3 {
+			continue
+		}
+		used, err := strconv.ParseFloat(strings.TrimSuffix(r[2], "%"), 64)
+		if err != nil {
+			continue
+		}
+		name := r[1]
+		if name == "" {
+			name = r[0]
+		}
+		vs[name] = used
+	}
+	return vs
+}
+
+// HandleEvent moves the cursor with the same keys as the process list.
+func (disk *DiskWidget) HandleEvent(e tui.Event) bool {
+	return navigate(disk.Table, e, sequence(&disk.previousKey, e.ID))
+}
+
+// Details describes the partition under the cursor.
+func (disk *DiskWidget) Details() (string, []string, bool) {
+	if disk.SelectedRow < 0 || disk.SelectedRow >= len(disk.Rows) {
+		return "", nil, false
+	}
+	row := disk.Rows[disk.SelectedRow]
+	var p *Partition
+	if disk.SelectedRow < len(disk.keys) {
+		p = disk.Partitions[disk.keys[disk.SelectedRow]]
+	}
+	if p == nil || p.Total == 0 {
+		// Remotes and daemons only provide the table
+		lines := make([]string, 0, len(row))
+		for i, h := range disk.Header {
+			if i < len(row) && row[i] != "" {
+				lines = append(lines, fmt.Sprintf("%-14s %s", h+":", row[i]))
+			}
+		}
+		return row[0], lines, true
+	}
+	line := func(key, value string) string {
+		return fmt.Sprintf("%-14s %s", tr.Value("widget.disk."+key)+":", value)
+	}
+	size := func(b uint64) string {
+		v, unit := utils.ConvertBytes(b)
+		return fmt.Sprintf("%.1f%s", v, unit)
+	}
+	lines := []string{
+		line("device", p.Device),
+		line("blockdevice", p.BlockDevice),
+		line("mount", p.MountPoint),
+		line("fstype", p.Fstype),
+		line("opts", strings.Join(p.Opts, ",")),
+		line("total", size(p.Total)),
+		line("used", fmt.Sprintf("%s (%d%%)", size(p.Used), p.UsedPercent)),
+		line("free", size(p.FreeBytes)),
+	}
+	if p.InodesTotal > 0 {
+		lines = append(lines, line("inodes", tr.Value("widget.disk.inodesof", strconv.FormatUint(p.InodesUsed, 10), strconv.FormatUint(p.InodesTotal, 10), strconv.FormatUint(p.InodesUsed*100/p.InodesTotal, 10))))
+	} else {
+		lines = append(lines, line("inodes", "-"))
+	}
+	return row[0], lines, true
+}
+
+// blockDevice returns the disk that a partition device is on, e.g. sda for
+// /dev/sda1, or the devices that a device mapper device is mapped onto. It
+// returns the device if that isn't known, e.g. when there's no /sys.
+func blockDevice(device string) string {
+	dev, err := filepath.EvalSymlinks(device)
+	if err != nil {
+		return device
+	}
+	sys := filepath.Join("/sys/class/block", filepath.Base(dev))
+	if slaves, err := ioutil.ReadDir(filepath.Join(sys, "slaves")); err == nil && len(slaves) > 0 {
+		names := make([]string, len(slaves))
+		for i, s := range slaves {
+			names[i] = blockDevice(filepath.Join("/dev", s.Name()))
+		}
+		return strings.Join(names, ",")
+	}
+	if _, err := os.Stat(filepath.Join(sys, "partition")); err == nil {
+		if parent, err := filepath.EvalSymlinks(filepath.Join(sys, "..")); err == nil {
+			return filepath.Base(parent)
+		}
 	}
+	return filepath.Base(dev)
 }
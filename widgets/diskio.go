@@ -0,0 +1,253 @@
+package widgets
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	tui "github.com/gizak/termui/v3"
+	psDisk "github.com/shirou/gopsutil/v3/disk"
+
+	"github.com/xxxserxxx/gotop/v4/devices"
+	ui "github.com/xxxserxxx/gotop/v4/termui"
+	"github.com/xxxserxxx/gotop/v4/utils"
+)
+
+// DiskIOGraph is the value plotted by the disk IO widget.
+type DiskIOGraph int
+
+const (
+	// DiskIOThroughput plots the bytes read and written per second
+	DiskIOThroughput DiskIOGraph = iota
+	// DiskIOIOPS plots the reads and writes per second
+	DiskIOIOPS
+	// DiskIOLatency plots the average time taken by a read or write
+	DiskIOLatency
+	// DiskIOUtil plots the percentage of time the disk was busy
+	DiskIOUtil
+	diskIOGraphs
+)
+
+// diskIO is the activity of one disk since the last update.
+type diskIO struct {
+	counters psDisk.IOCountersStat
+	updated  time.Time
+
+	ReadBytes  float64 // per second
+	WriteBytes float64 // per second
+	IOPS       float64
+	Latency    float64 // milliseconds
+	Util       float64 // percent
+
+	// history holds the data points of each graph; latency is in
+	// microseconds, so that it plots as an int
+	history [diskIOGraphs][]int
+	line    *ui.Sparkline
+}
+
+// DiskIOWidget graphs the throughput, IOPS, latency, or utilization of each
+// disk. Unlike the disk widget, it shows whole disks rather than partitions.
+type DiskIOWidget struct {
+	*ui.SparklineGroup
+	updateInterval time.Duration
+	// Graph is the value plotted; the others are shown in the titles
+	Graph DiskIOGraph
+	// Colors are used in turn for the disks' sparklines
+	Colors     []tui.Color
+	TitleColor tui.Color
+
+	disks   map[string]*diskIO
+	metrics bool
+	host    string
+}
+
+// NewDiskIOWidget creates a disk IO graph for host. Remotes don't report disk
+// IO, so for them the widget only says so.
+func NewDiskIOWidget(host string) *DiskIOWidget {
+	self := &DiskIOWidget{
+		SparklineGroup: ui.NewSparklineGroup(),
+		updateInterval: time.Second,
+		Colors:         []tui.Color{tui.ColorGreen, tui.ColorCyan},
+		TitleColor:     tui.ColorWhite,
+		disks:          make(map[string]*diskIO),
+		host:           host,
+	}
+	self.setTitle()
+
+	if passive || host != devices.Local {
+		return self
+	}
+
+	self.update()
+
+	go func() {
+		for range time.NewTicker(self.updateInterval).C {
+			self.Lock()
+			self.update()
+			self.Unlock()
+		}
+	}()
+
+	return self
+}
+
+// EnableMetric exports the throughput, IOPS, latency, and utilization of
+// each disk, including disks that appear later.
+func (dio *DiskIOWidget) EnableMetric() {
+	dio.metrics = true
+	for name := range dio.disks {
+		dio.enableMetric(name)
+	}
+}
+
+// enableMetric exports the disk's activity. The gauges look the disk up
+// rather than holding on to it, because they outlive it if it's removed, and
+// are reused if it's attached again; they read 0 while it's gone.
+func (dio *DiskIOWidget) enableMetric(name string) {
+	gauge := func(key string, value func(d *diskIO) float64) {
+		metrics.GetOrCreateGauge(makeName("diskio", name, key), func() float64 {
+			dio.Lock()
+			defer dio.Unlock()
+			if d, ok := dio.disks[name]; ok {
+				return value(d)
+			}
+			return 0
+		})
+	}
+	gauge("read_bytes", func(d *diskIO) float64 { return d.ReadBytes })
+	gauge("write_bytes", func(d *diskIO) float64 { return d.WriteBytes })
+	gauge("iops", func(d *diskIO) float64 { return d.IOPS })
+	gauge("latency_seconds", func(d *diskIO) float64 { return d.Latency / 1000 })
+	gauge("util", func(d *diskIO) float64 { return d.Util / 100 })
+}
+
+// HandleEvent shows the next graph with i. A UI attached to a daemon shows
+// the daemon's graph.
+func (dio *DiskIOWidget) HandleEvent(e tui.Event) bool {
+	if e.ID != "i" || passive {
+		return false
+	}
+	dio.Graph = (dio.Graph + 1) % diskIOGraphs
+	dio.setTitle()
+	for _, d := range dio.disks {
+		d.line.Data = d.history[dio.Graph]
+	}
+	return true
+}
+
+// MaxLabel formats the value of a full bar of the graph shown, for the
+// legends of the graphs.
+func (dio *DiskIOWidget) MaxLabel(v int) string {
+	var value string
+	switch dio.Graph {
+	case DiskIOThroughput:
+		r, unit := utils.ConvertBytes(uint64(v))
+		value = fmt.Sprintf("%.1f %s/s", r, unit)
+	case DiskIOIOPS:
+		value = fmt.Sprintf("%d IOPS", v)
+	case DiskIOLatency:
+		value = fmt.Sprintf("%.2f ms", float64(v)/1000)
+	case DiskIOUtil:
+		value = fmt.Sprintf("%d%%", v)
+	}
+	return fmt.Sprintf(" %s %s ", tr.Value("max"), value)
+}
+
+func (dio *DiskIOWidget) setTitle() {
+	if dio.host != devices.Local {
+		dio.Title = tr.Value("widget.diskio.remote", dio.host)
+		return
+	}
+	graphs := []string{"throughput", "iops", "latency", "util"}
+	dio.Title = tr.Value("widget.label.diskio", tr.Value("widget.diskio."+graphs[dio.Graph]))
+}
+
+func (dio *DiskIOWidget) update() {
+	counters, err := devices.DiskIO()
+	if err != nil {
+		log.Printf(tr.Value("error.recovfetch", "diskio", err.Error()))
+		return
+	}
+	now := time.Now()
+	for name, c := range counters {
+		d, ok := dio.disks[name]
+		if !ok {
+			d = &diskIO{counters: c, updated: now, line: ui.NewSparkline()}
+			dio.disks[name] = d
+			if dio.metrics {
+				dio.enableMetric(name)
+			}
+			continue
+		}
+		d.update(c, now)
+		d.line.Data = d.history[dio.Graph]
+	}
+	for name := range dio.disks {
+		if _, ok := counters[name]; !ok {
+			delete(dio.disks, name)
+		}
+	}
+	dio.render()
+}
+
+// update computes the disk's activity since the previous counters, and adds
+// it to the graphs.
+func (d *diskIO) update(c psDisk.IOCountersStat, now time.Time) {
+	secs := now.Sub(d.updated).Seconds()
+	if secs <= 0 {
+		return
+	}
+	prev := d.counters
+	d.counters, d.updated = c, now
+	// counters are reset when a disk is reattached
+	if c.ReadCount < prev.ReadCount || c.WriteCount < prev.WriteCount || c.IoTime < prev.IoTime {
+		return
+	}
+	ops := (c.ReadCount - prev.ReadCount) + (c.WriteCount - prev.WriteCount)
+	d.ReadBytes = float64(c.ReadBytes-prev.ReadBytes) / secs
+	d.WriteBytes = float64(c.WriteBytes-prev.WriteBytes) / secs
+	d.IOPS = float64(ops) / secs
+	d.Latency = 0
+	if ops > 0 {
+		d.Latency = float64((c.ReadTime-prev.ReadTime)+(c.WriteTime-prev.WriteTime)) / float64(ops)
+	}
+	// IoTime is the milliseconds the disk was busy
+	d.Util = float64(c.IoTime-prev.IoTime) / (secs * 10)
+	if d.Util > 100 {
+		d.Util = 100
+	}
+
+	points := [diskIOGraphs]int{
+		DiskIOThroughput: int(d.ReadBytes + d.WriteBytes),
+		DiskIOIOPS:       int(d.IOPS + 0.5),
+		DiskIOLatency:    int(d.Latency * 1000),
+		DiskIOUtil:       int(d.Util + 0.5),
+	}
+	for i, p := range points {
+		d.history[i] = append(d.history[i], p)
+	}
+}
+
+// render orders the sparklines by disk name, and updates their titles.
+func (dio *DiskIOWidget) render() {
+	names := make([]string, 0, len(dio.disks))
+	for name := range dio.disks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	dio.Lines = dio.Lines[:0]
+	for i, name := range names {
+		d := dio.disks[name]
+		read, readUnit := utils.ConvertBytes(uint64(d.ReadBytes))
+		write, writeUnit := utils.ConvertBytes(uint64(d.WriteBytes))
+		d.line.Title1 = fmt.Sprintf(" %s  R: %5.1f %s/s  W: %5.1f %s/s", name, read, readUnit, write, writeUnit)
+		d.line.Title2 = fmt.Sprintf(" %6.0f IOPS  %6.2f ms  %3.0f%% %s", d.IOPS, d.Latency, d.Util, tr.Value("widget.diskio.busy"))
+		d.line.TitleColor = dio.TitleColor
+		if len(dio.Colors) > 0 {
+			d.line.LineColor = dio.Colors[i%len(dio.Colors)]
+		}
+		dio.Lines = append(dio.Lines, d.line)
+	}
+}
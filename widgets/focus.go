@@ -0,0 +1,69 @@
+package widgets
+
+import (
+	ui "github.com/gizak/termui/v3"
+
+	"github.com/xxxserxxx/gotop/v4/termui"
+)
+
+// FocusColor is the border colour of the focused widget.
+var FocusColor = ui.ColorCyan
+
+// Interactive widgets handle keyboard and mouse events. Events are offered to
+// the focused widget first, and then to the other widgets of the page, so
+// HandleEvent must return false for events the widget doesn't use.
+// HandleEvent must be called with the widget locked.
+type Interactive interface {
+	// HandleEvent handles the event, returning true if it did, in which case
+	// the widget needs to be drawn.
+	HandleEvent(e ui.Event) bool
+	Lock()
+	Unlock()
+}
+
+// navigate moves the cursor of the table for the navigation keys, the mouse
+// wheel, and clicks, returning false for any other event. previous is the
+// key pressed before e, for gg.
+func navigate(t *termui.Table, e ui.Event, previous string) bool {
+	switch e.ID {
+	case "k", "<Up>", "<MouseWheelUp>":
+		t.ScrollUp()
+	case "j", "<Down>", "<MouseWheelDown>":
+		t.ScrollDown()
+	case "<Home>":
+		t.ScrollTop()
+	case "g":
+		if previous == "g" {
+			t.ScrollTop()
+		}
+	case "G", "<End>":
+		t.ScrollBottom()
+	case "<C-d>":
+		t.ScrollHalfPageDown()
+	case "<C-u>":
+		t.ScrollHalfPageUp()
+	case "<C-f>", "<PageDown>":
+		t.ScrollPageDown()
+	case "<C-b>", "<PageUp>":
+		t.ScrollPageUp()
+	case "<MouseLeft>":
+		m := e.Payload.(ui.Mouse)
+		t.HandleClick(m.X, m.Y)
+	default:
+		return false
+	}
+	return true
+}
+
+// sequence records key as the last key pressed, and returns the key pressed
+// before it. A key pressed twice in a row isn't recorded the second time, so
+// that ggg only jumps to the top once.
+func sequence(last *string, key string) string {
+	previous := *last
+	if previous == key {
+		*last = ""
+	} else {
+		*last = key
+	}
+	return previous
+}
@@ -0,0 +1,72 @@
+package widgets
+
+import (
+	"os"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+
+	"github.com/xxxserxxx/gotop/v4/devices"
+)
+
+// HostMenu is a pop-up for choosing the host that the UI displays.
+type HostMenu struct {
+	widgets.List
+	hosts []string
+}
+
+func NewHostMenu() *HostMenu {
+	menu := &HostMenu{
+		List: *widgets.NewList(),
+	}
+	menu.Title = tr.Value("widget.label.hostmenu")
+	menu.SelectedRowStyle = ui.NewStyle(ui.Theme.Default.Fg, ui.ColorClear, ui.ModifierReverse)
+	return menu
+}
+
+// SetHosts fills the menu with the local host and the remotes, and moves the
+// cursor to the current host.
+func (menu *HostMenu) SetHosts(remotes []string, current string) {
+	local, err := os.Hostname()
+	if err != nil {
+		local = "localhost"
+	}
+	menu.hosts = append([]string{devices.Local}, remotes...)
+	menu.Rows = make([]string, len(menu.hosts))
+	menu.SelectedRow = 0
+	for i, h := range menu.hosts {
+		if h == devices.Local {
+			menu.Rows[i] = tr.Value("widget.hostmenu.local", local)
+		} else {
+			menu.Rows[i] = h
+		}
+		if h == current {
+			menu.SelectedRow = i
+		}
+	}
+}
+
+// Selected returns the host under the cursor.
+func (menu *HostMenu) Selected() string {
+	if menu.SelectedRow < 0 || menu.SelectedRow >= len(menu.hosts) {
+		return devices.Local
+	}
+	return menu.hosts[menu.SelectedRow]
+}
+
+func (menu *HostMenu) Resize(termWidth, termHeight int) {
+	textWidth := 30
+	for _, r := range menu.Rows {
+		if textWidth < len(r)+4 {
+			textWidth = len(r) + 4
+		}
+	}
+	textHeight := len(menu.Rows) + 2
+	if textHeight > termHeight {
+		textHeight = termHeight
+	}
+	x := (termWidth - textWidth) / 2
+	y := (termHeight - textHeight) / 2
+
+	menu.List.SetRect(x, y, textWidth+x, textHeight+y)
+}
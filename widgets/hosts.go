@@ -0,0 +1,187 @@
+package widgets
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tui "github.com/gizak/termui/v3"
+
+	"github.com/xxxserxxx/gotop/v4/devices"
+	ui "github.com/xxxserxxx/gotop/v4/termui"
+	"github.com/xxxserxxx/gotop/v4/utils"
+)
+
+// Columns of the hosts table; these are also the sort methods
+const (
+	HostSortName = iota
+	HostSortCPU
+	HostSortMem
+	HostSortTemp
+	HostSortRecv
+	HostSortSent
+	HostSortState
+)
+
+// HostsWidget shows a summary of each remote gotop, one host per row.
+type HostsWidget struct {
+	*ui.Table
+	updateInterval time.Duration
+	TempScale      TempScale
+	sortMethod     int
+	hosts          []devices.RemoteHost
+	previousKey    string
+}
+
+func NewHostsWidget(tempScale TempScale) *HostsWidget {
+	self := &HostsWidget{
+		Table:          ui.NewTable(),
+		updateInterval: time.Second,
+		TempScale:      tempScale,
+		sortMethod:     HostSortName,
+	}
+	self.Table.Tr = tr
+	self.Title = tr.Value("widget.label.hosts")
+	self.ShowCursor = true
+	self.ShowLocation = true
+	self.ColGap = 2
+	self.PadLeft = 1
+	self.UniqueCol = HostSortName
+	self.ColResizer = func() {
+		self.ColWidths = []int{
+			utils.MaxInt(6, self.Inner.Dx()-55), 5, 5, 5, 9, 9, 5,
+		}
+	}
+
+	if passive {
+		return self
+	}
+
+	self.update()
+
+	go func() {
+		for range time.NewTicker(self.updateInterval).C {
+			self.Lock()
+			self.update()
+			self.Unlock()
+		}
+	}()
+
+	return self
+}
+
+func (h *HostsWidget) EnableMetric() {
+	// Remote data is exported by the remotes themselves
+}
+
+// Selected returns the name of the host under the cursor, or "" if there are
+// no hosts.
+func (h *HostsWidget) Selected() string {
+	if h.SelectedRow < 0 || h.SelectedRow >= len(h.Rows) {
+		return ""
+	}
+	return h.Rows[h.SelectedRow][h.UniqueCol]
+}
+
+// ChangeSortMethod sorts the table by one of the HostSort* columns.
+func (h *HostsWidget) ChangeSortMethod(method int) {
+	if method < HostSortName || method > HostSortState || method == h.sortMethod {
+		return
+	}
+	h.sortMethod = method
+	h.ScrollTop()
+	h.render()
+}
+
+// HandleClick sorts by the column if the header was clicked, and otherwise
+// moves the cursor to the clicked row.
+func (h *HostsWidget) HandleClick(x, y int) {
+	if !(x >= h.Inner.Min.X && x < h.Inner.Max.X && y == h.Inner.Min.Y) {
+		h.Table.HandleClick(x, y)
+		return
+	}
+	cur := h.Inner.Min.X + h.PadLeft
+	for i, w := range h.ColWidths {
+		if x < cur+w+h.ColGap {
+			h.ChangeSortMethod(i)
+			return
+		}
+		cur += w + h.ColGap
+	}
+}
+
+// HandleEvent handles navigation, and clicks on rows and column headers.
+func (h *HostsWidget) HandleEvent(e tui.Event) bool {
+	previous := sequence(&h.previousKey, e.ID)
+	if e.ID == "<MouseLeft>" {
+		m := e.Payload.(tui.Mouse)
+		h.HandleClick(m.X, m.Y)
+		return true
+	}
+	return navigate(h.Table, e, previous)
+}
+
+func (h *HostsWidget) update() {
+	h.hosts = devices.RemoteHosts()
+	h.render()
+}
+
+func (h *HostsWidget) render() {
+	h.Header = []string{
+		tr.Value("widget.hosts.host"),
+		tr.Value("widget.hosts.cpu"),
+		tr.Value("widget.hosts.mem"),
+		tr.Value("widget.hosts.temp"),
+		tr.Value("widget.hosts.rx"),
+		tr.Value("widget.hosts.tx"),
+		tr.Value("widget.hosts.state"),
+	}
+	h.Header[h.sortMethod] += _downArrow
+
+	sort.SliceStable(h.hosts, func(i, j int) bool {
+		a, b := h.hosts[i], h.hosts[j]
+		switch h.sortMethod {
+		case HostSortCPU:
+			return a.CPU > b.CPU
+		case HostSortMem:
+			return a.Mem > b.Mem
+		case HostSortTemp:
+			return a.MaxTemp > b.MaxTemp
+		case HostSortRecv:
+			return a.RecvRate > b.RecvRate
+		case HostSortSent:
+			return a.SentRate > b.SentRate
+		case HostSortState:
+			return !a.Reachable && b.Reachable
+		default:
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		}
+	})
+
+	rows := make([][]string, len(h.hosts))
+	for i, host := range h.hosts {
+		temp := host.MaxTemp
+		if h.TempScale == Fahrenheit {
+			temp = utils.CelsiusToFahrenheit(temp)
+		}
+		// The header says the rates are per second
+		rx, rxUnit := utils.ConvertRate(uint64(host.RecvRate))
+		tx, txUnit := utils.ConvertRate(uint64(host.SentRate))
+		rxUnit, txUnit = strings.TrimSuffix(rxUnit, "/s"), strings.TrimSuffix(txUnit, "/s")
+		state := tr.Value("widget.hosts.up")
+		if !host.Reachable {
+			state = tr.Value("widget.hosts.down")
+		}
+		rows[i] = []string{
+			host.Name,
+			fmt.Sprintf("%3.0f%%", host.CPU),
+			fmt.Sprintf("%3.0f%%", host.Mem),
+			fmt.Sprintf("%3d°%c", temp, h.TempScale),
+			fmt.Sprintf("%5.1f%s", rx, rxUnit),
+			fmt.Sprintf("%5.1f%s", tx, txUnit),
+			state,
+		}
+	}
+	h.Rows = rows
+}
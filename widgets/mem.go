@@ -14,17 +14,23 @@ import (
 type MemWidget struct {
 	*ui.LineGraph
 	updateInterval time.Duration
+	host           string
+	critical       hysteresis
 }
 
-func NewMemWidget(updateInterval time.Duration, horizontalScale int) *MemWidget {
+// NewMemWidget creates a memory use graph for host, which is either the name
+// of a remote or devices.Local.
+func NewMemWidget(updateInterval time.Duration, horizontalScale int, host string) *MemWidget {
 	widg := &MemWidget{
 		LineGraph:      ui.NewLineGraph(),
 		updateInterval: updateInterval,
+		host:           host,
+		critical:       make(hysteresis),
 	}
 	widg.Title = tr.Value("widget.label.mem")
 	widg.HorizontalScale = horizontalScale
 	mems := make(map[string]devices.MemoryInfo)
-	devices.UpdateMem(mems)
+	devices.HostMem(host, mems)
 	for name, mem := range mems {
 		if mem.Total > 0 {
 			widg.Data[name] = []float64{0}
@@ -32,10 +38,14 @@ func NewMemWidget(updateInterval time.Duration, horizontalScale int) *MemWidget
 		}
 	}
 
+	if passive {
+		return widg
+	}
+
 	go func() {
 		for range time.NewTicker(widg.updateInterval).C {
 			widg.Lock()
-			devices.UpdateMem(mems)
+			devices.HostMem(widg.host, mems)
 			for label, mi := range mems {
 				if mi.Total > 0 {
 					widg.renderMemInfo(label, mi)
@@ -53,7 +63,7 @@ func (mem *MemWidget) EnableMetric() {
 	devices.UpdateMem(mems)
 	for l := range mems {
 		lc := l
-		metrics.NewGauge(makeName("memory", l), func() float64 {
+		metrics.GetOrCreateGauge(makeName("memory", l), func() float64 {
 			if ds, ok := mem.Data[lc]; ok {
 				return ds[len(ds)-1]
 			}
@@ -68,6 +78,11 @@ func (mem *MemWidget) Scale(i int) {
 
 func (mem *MemWidget) renderMemInfo(line string, memoryInfo devices.MemoryInfo) {
 	mem.Data[line] = append(mem.Data[line], memoryInfo.UsedPercent)
+	if mem.host != devices.Local {
+		// Remotes only report percentages
+		mem.Labels[line] = fmt.Sprintf("%3.0f%%", memoryInfo.UsedPercent)
+		return
+	}
 	memoryTotalBytes, memoryTotalMagnitude := utils.ConvertBytes(memoryInfo.Total)
 	memoryUsedBytes, memoryUsedMagnitude := utils.ConvertBytes(memoryInfo.Used)
 	mem.Labels[line] = fmt.Sprintf("%3.0f%% %5.1f%s/%.0f%s",
@@ -2,18 +2,24 @@ package widgets
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
+// invalidMetric matches the characters that can't be used in metric names,
+// such as the . of a VLAN interface like eth0.100.
+var invalidMetric = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+
 // makeName creates a prometheus metric name in the gotop space
 // This function doesn't have to be very efficient because it's only
 // called at init time, and only a few dozen times... and it isn't
-// (very efficient).
+// (very efficient). Characters that aren't allowed in metric names, like the
+// - in dm-0, are replaced by _, so parts needn't be sanitized beforehand.
 func makeName(parts ...interface{}) string {
 	args := make([]string, len(parts)+1)
 	args[0] = "gotop"
 	for i, v := range parts {
 		args[i+1] = fmt.Sprintf("%v", v)
 	}
-	return strings.Join(args, "_")
+	return invalidMetric.ReplaceAllString(strings.Join(args, "_"), "_")
 }
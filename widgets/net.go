@@ -7,8 +7,10 @@ import (
 	"time"
 
 	"github.com/VictoriaMetrics/metrics"
+	tui "github.com/gizak/termui/v3"
 	psNet "github.com/shirou/gopsutil/v3/net"
 
+	"github.com/xxxserxxx/gotop/v4/devices"
 	ui "github.com/xxxserxxx/gotop/v4/termui"
 	"github.com/xxxserxxx/gotop/v4/utils"
 )
@@ -30,11 +32,27 @@ type NetWidget struct {
 	NetInterface   []string
 	sentMetric     *metrics.Counter
 	recvMetric     *metrics.Counter
-	Mbps           bool
+	// Units default to those set by utils.SetUnits; b toggles Units.Bits
+	Units utils.Units
+	// Packets shows the packets, errors, and drops per second of the local
+	// interfaces below the rates; errors and drops are in AlertColor.
+	Packets bool
+	// packets are the totals of the last update, in the order of
+	// packetMetricNames; nil before the first update
+	packets       []uint64
+	recentPackets []uint64
+	packetMetrics []*metrics.Counter
+	// Name is added to the metric names, to distinguish net widgets that
+	// monitor different interfaces
+	Name string
+	host string
 }
 
+// NewNetWidget creates a network graph for host, which is either the name of a
+// remote or devices.Local. The interfaces only apply to the local host;
+// remotes report the totals of the interfaces they monitor.
 // TODO: state:merge #169 % option for network use (jrswab/networkPercentage)
-func NewNetWidget(netInterface string) *NetWidget {
+func NewNetWidget(netInterface string, host string) *NetWidget {
 	recvSparkline := ui.NewSparkline()
 	recvSparkline.Data = []int{}
 
@@ -46,12 +64,18 @@ func NewNetWidget(netInterface string) *NetWidget {
 		SparklineGroup: spark,
 		updateInterval: time.Second,
 		NetInterface:   strings.Split(netInterface, ","),
+		Units:          utils.GetUnits(),
+		host:           host,
 	}
 	self.Title = tr.Value("widget.label.net")
-	if netInterface != "all" {
+	if netInterface != "all" && host == devices.Local {
 		self.Title = tr.Value("widget.label.netint", netInterface)
 	}
 
+	if passive {
+		return self
+	}
+
 	self.update()
 
 	go func() {
@@ -65,47 +89,115 @@ func NewNetWidget(netInterface string) *NetWidget {
 	return self
 }
 
+// packetMetricNames are the metrics of the packet counters of the local
+// interfaces.
+var packetMetricNames = []string{"recv_packets", "recv_errors", "recv_drops", "sent_packets", "sent_errors", "sent_drops"}
+
 func (net *NetWidget) EnableMetric() {
-	net.recvMetric = metrics.NewCounter(makeName("net", "recv"))
-	net.sentMetric = metrics.NewCounter(makeName("net", "sent"))
+	name := func(key string) string {
+		if net.Name != "" {
+			return makeName("net", key, net.Name)
+		}
+		return makeName("net", key)
+	}
+	net.recvMetric = metrics.GetOrCreateCounter(name("recv"))
+	net.sentMetric = metrics.GetOrCreateCounter(name("sent"))
+	// Remotes only report bytes
+	if net.host != devices.Local {
+		return
+	}
+	net.packetMetrics = make([]*metrics.Counter, len(packetMetricNames))
+	for i, key := range packetMetricNames {
+		net.packetMetrics[i] = metrics.GetOrCreateCounter(name(key))
+	}
+}
+
+// MaxLabel formats the rate of a full bar, for the legend of the graph.
+func (net *NetWidget) MaxLabel(v int) string {
+	return rateLabel(net.Units, v)
+}
+
+// rateLabel formats the bytes per second of a full bar of a network graph.
+func rateLabel(u utils.Units, v int) string {
+	r, unit := u.Rate(uint64(v))
+	return fmt.Sprintf(" %s %.1f %s ", tr.Value("max"), r, unit)
+}
+
+// HandleEvent toggles between showing bits and bytes per second with b.
+func (net *NetWidget) HandleEvent(e tui.Event) bool {
+	if e.ID != "b" {
+		return false
+	}
+	net.Units.Bits = !net.Units.Bits
+	return true
 }
 
 func (net *NetWidget) update() {
-	interfaces, err := psNet.IOCounters(true)
+	if net.host != devices.Local {
+		net.render(devices.RemoteNet(net.host))
+		return
+	}
+	total, err := net.counters()
 	if err != nil {
 		log.Println(tr.Value("widget.net.err.netactivity", err.Error()))
 		return
 	}
+	net.render(total.BytesRecv, total.BytesSent)
+	net.renderPackets(total)
+}
 
-	var totalBytesRecv uint64
-	var totalBytesSent uint64
-	interfaceMap := make(map[string]bool)
-	// Default behaviour
-	interfaceMap[NetInterfaceAll] = true
-	interfaceMap[NetInterfaceVpn] = false
-	// Build a map with wanted status for each interfaces.
-	for _, iface := range net.NetInterface {
-		if strings.HasPrefix(iface, "!") {
-			interfaceMap[strings.TrimPrefix(iface, "!")] = false
-		} else {
-			// if we specify a wanted interface, remove capture on all.
-			delete(interfaceMap, NetInterfaceAll)
-			interfaceMap[iface] = true
-		}
+// counters returns the totals of the selected local interfaces.
+func (net *NetWidget) counters() (psNet.IOCountersStat, error) {
+	var total psNet.IOCountersStat
+	interfaces, err := psNet.IOCounters(true)
+	if err != nil {
+		return total, err
 	}
+
 	for _, _interface := range interfaces {
-		wanted, ok := interfaceMap[_interface.Name]
-		if wanted && ok { // Simple case
-			totalBytesRecv += _interface.BytesRecv
-			totalBytesSent += _interface.BytesSent
-		} else if ok { // Present but unwanted
-			continue
-		} else if interfaceMap[NetInterfaceAll] { // Capture other
-			totalBytesRecv += _interface.BytesRecv
-			totalBytesSent += _interface.BytesSent
+		if wantedInterface(net.NetInterface, _interface.Name) {
+			total.BytesRecv += _interface.BytesRecv
+			total.BytesSent += _interface.BytesSent
+			total.PacketsRecv += _interface.PacketsRecv
+			total.PacketsSent += _interface.PacketsSent
+			total.Errin += _interface.Errin
+			total.Errout += _interface.Errout
+			total.Dropin += _interface.Dropin
+			total.Dropout += _interface.Dropout
+		}
+	}
+	return total, nil
+}
+
+// wantedInterface reports whether the interface is selected by the patterns,
+// which are globs, all, or globs prefixed by ! to exclude interfaces. Without
+// any patterns but exclusions, all interfaces are selected; the VPN is only
+// selected if a pattern other than all matches it.
+func wantedInterface(patterns []string, name string) bool {
+	globs := make([]string, len(patterns))
+	for i, p := range patterns {
+		globs[i] = p
+		if p == NetInterfaceAll {
+			globs[i] = "*"
+		}
+	}
+	if !utils.GlobFilter(globs, name) {
+		return false
+	}
+	if name != NetInterfaceVpn {
+		return true
+	}
+	for _, p := range patterns {
+		if p != NetInterfaceAll && !strings.HasPrefix(p, "!") && utils.Glob(p, name) {
+			return true
 		}
 	}
+	return false
+}
 
+// render adds the activity since the last update to the graphs, and updates
+// the titles.
+func (net *NetWidget) render(totalBytesRecv, totalBytesSent uint64) {
 	var recentBytesRecv uint64
 	var recentBytesSent uint64
 
@@ -138,31 +230,52 @@ func (net *NetWidget) update() {
 	net.totalBytesRecv = totalBytesRecv
 	net.totalBytesSent = totalBytesSent
 
-	rx, tx := "RX/s", "TX/s"
-	if net.Mbps {
-		rx, tx = "mbps", "mbps"
-	}
-	format := " %s: %9.1f %2s/s"
-
 	var total, recent uint64
-	var label, unitRecent, rate string
-	var recentConverted float64
+	var label string
 	// render widget titles
 	for i := 0; i < 2; i++ {
 		if i == 0 {
-			total, label, rate, recent = totalBytesRecv, "RX", rx, recentBytesRecv
+			total, label, recent = totalBytesRecv, "RX", recentBytesRecv
 		} else {
-			total, label, rate, recent = totalBytesSent, "TX", tx, recentBytesSent
+			total, label, recent = totalBytesSent, "TX", recentBytesSent
 		}
 
-		totalConverted, unitTotal := utils.ConvertBytes(total)
-		if net.Mbps {
-			recentConverted, unitRecent, format = float64(recent)*0.000008, "", " %s: %11.3f %2s"
-		} else {
-			recentConverted, unitRecent = utils.ConvertBytes(recent)
-		}
+		totalConverted, unitTotal := net.Units.Bytes(total)
+		recentConverted, unitRecent := net.Units.Rate(recent)
 
 		net.Lines[i].Title1 = fmt.Sprintf(" %s %s: %5.1f %s", tr.Value("total"), label, totalConverted, unitTotal)
-		net.Lines[i].Title2 = fmt.Sprintf(format, rate, recentConverted, unitRecent)
+		net.Lines[i].Title2 = fmt.Sprintf(" %s/s: %9.1f %s", label, recentConverted, unitRecent)
+	}
+}
+
+// renderPackets updates the packet metrics and, if Packets is set, the third
+// titles with the packets, errors, and drops since the last update.
+func (net *NetWidget) renderPackets(total psNet.IOCountersStat) {
+	counts := []uint64{total.PacketsRecv, total.Errin, total.Dropin, total.PacketsSent, total.Errout, total.Dropout}
+	recent := make([]uint64, len(counts))
+	if net.packets != nil {
+		for i, c := range counts {
+			// counters go back to 0 when an interface is recreated
+			if c >= net.packets[i] {
+				recent[i] = c - net.packets[i]
+			}
+			if net.packetMetrics != nil {
+				net.packetMetrics[i].Add(int(recent[i]))
+			}
+		}
+	}
+	net.packets, net.recentPackets = counts, recent
+
+	for i := 0; i < 2; i++ {
+		l, r := net.Lines[i], recent[i*3:i*3+3]
+		l.Title3, l.Title3Color = "", tui.ColorClear
+		if !net.Packets {
+			continue
+		}
+		l.Title3 = fmt.Sprintf(" %s: %7d  %s: %d  %s: %d",
+			tr.Value("widget.net.packets"), r[0], tr.Value("widget.net.errors"), r[1], tr.Value("widget.net.drops"), r[2])
+		if r[1] > 0 || r[2] > 0 {
+			l.Title3Color = AlertColor
+		}
 	}
 }
@@ -0,0 +1,202 @@
+package widgets
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	tui "github.com/gizak/termui/v3"
+	psNet "github.com/shirou/gopsutil/v3/net"
+
+	"github.com/xxxserxxx/gotop/v4/devices"
+	ui "github.com/xxxserxxx/gotop/v4/termui"
+	"github.com/xxxserxxx/gotop/v4/utils"
+)
+
+// netIf is the activity of one network interface.
+type netIf struct {
+	recv, sent             *ui.Sparkline
+	totalRecv, totalSent   uint64
+	recentRecv, recentSent uint64
+	state, addr            string
+	recvMetric, sentMetric *metrics.Counter
+}
+
+// NetIfWidget graphs the traffic of each network interface separately, with
+// its link state and address, so that it's clear which interface the traffic
+// is on.
+type NetIfWidget struct {
+	*ui.SparklineGroup
+	updateInterval time.Duration
+	// NetInterface are the patterns of the interfaces shown, as for NetWidget
+	NetInterface []string
+	RecvColor    tui.Color
+	SentColor    tui.Color
+	TitleColor   tui.Color
+
+	ifaces  map[string]*netIf
+	metrics bool
+	host    string
+}
+
+// NewNetIfWidget creates per-interface network graphs for host. Remotes only
+// report their totals, so for them the widget only says so.
+func NewNetIfWidget(netInterface string, host string) *NetIfWidget {
+	self := &NetIfWidget{
+		SparklineGroup: ui.NewSparklineGroup(),
+		updateInterval: time.Second,
+		NetInterface:   strings.Split(netInterface, ","),
+		RecvColor:      tui.ColorGreen,
+		SentColor:      tui.ColorCyan,
+		TitleColor:     tui.ColorWhite,
+		ifaces:         make(map[string]*netIf),
+		host:           host,
+	}
+	self.Title = tr.Value("widget.label.netif")
+	if host != devices.Local {
+		self.Title = tr.Value("widget.netif.remote", host)
+	}
+
+	if passive || host != devices.Local {
+		return self
+	}
+
+	self.update()
+
+	go func() {
+		for range time.NewTicker(self.updateInterval).C {
+			self.Lock()
+			self.update()
+			self.Unlock()
+		}
+	}()
+
+	return self
+}
+
+// EnableMetric exports the bytes received and sent by each interface,
+// including interfaces that appear later.
+func (n *NetIfWidget) EnableMetric() {
+	n.metrics = true
+	for name, i := range n.ifaces {
+		n.enableMetric(name, i)
+	}
+}
+
+func (n *NetIfWidget) enableMetric(name string, i *netIf) {
+	i.recvMetric = metrics.GetOrCreateCounter(makeName("netif", name, "recv"))
+	i.sentMetric = metrics.GetOrCreateCounter(makeName("netif", name, "sent"))
+}
+
+func (n *NetIfWidget) update() {
+	counters, err := psNet.IOCounters(true)
+	if err != nil {
+		log.Println(tr.Value("widget.net.err.netactivity", err.Error()))
+		return
+	}
+	stats := make(map[string]psNet.InterfaceStat)
+	if is, err := psNet.Interfaces(); err == nil {
+		for _, s := range is {
+			stats[s.Name] = s
+		}
+	}
+	seen := make(map[string]bool)
+	for _, c := range counters {
+		if !wantedInterface(n.NetInterface, c.Name) {
+			continue
+		}
+		seen[c.Name] = true
+		i, ok := n.ifaces[c.Name]
+		if !ok {
+			i = &netIf{recv: ui.NewSparkline(), sent: ui.NewSparkline()}
+			n.ifaces[c.Name] = i
+			if n.metrics {
+				n.enableMetric(c.Name, i)
+			}
+		} else {
+			// counters go back to 0 when an interface is recreated
+			i.recentRecv, i.recentSent = 0, 0
+			if c.BytesRecv >= i.totalRecv && c.BytesSent >= i.totalSent {
+				i.recentRecv, i.recentSent = c.BytesRecv-i.totalRecv, c.BytesSent-i.totalSent
+			}
+			i.recv.Data = append(i.recv.Data, int(i.recentRecv))
+			i.sent.Data = append(i.sent.Data, int(i.recentSent))
+			if i.recvMetric != nil {
+				i.recvMetric.Add(int(i.recentRecv))
+				i.sentMetric.Add(int(i.recentSent))
+			}
+		}
+		i.totalRecv, i.totalSent = c.BytesRecv, c.BytesSent
+		i.state, i.addr = linkState(stats[c.Name]), address(stats[c.Name])
+	}
+	for name := range n.ifaces {
+		if !seen[name] {
+			delete(n.ifaces, name)
+		}
+	}
+	n.render()
+}
+
+// linkState returns the operational state of the interface, where the OS
+// reports it, or else whether it's up.
+func linkState(s psNet.InterfaceStat) string {
+	if bs, err := ioutil.ReadFile(filepath.Join("/sys/class/net", s.Name, "operstate")); err == nil {
+		if state := strings.TrimSpace(string(bs)); state != "unknown" {
+			return state
+		}
+	}
+	for _, f := range s.Flags {
+		if f == "up" {
+			return "up"
+		}
+	}
+	return "down"
+}
+
+// address returns the first IPv4 address of the interface, or else its first
+// address.
+func address(s psNet.InterfaceStat) string {
+	for _, a := range s.Addrs {
+		if !strings.Contains(a.Addr, ":") {
+			return a.Addr
+		}
+	}
+	if len(s.Addrs) > 0 {
+		return s.Addrs[0].Addr
+	}
+	return ""
+}
+
+// MaxLabel formats the rate of a full bar, for the legends of the graphs.
+func (n *NetIfWidget) MaxLabel(v int) string {
+	return rateLabel(utils.GetUnits(), v)
+}
+
+// render orders the sparklines by interface name, and updates their titles.
+func (n *NetIfWidget) render() {
+	names := make([]string, 0, len(n.ifaces))
+	for name := range n.ifaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	n.Lines = n.Lines[:0]
+	rate := func(label string, recent, total uint64) string {
+		r, ru := utils.ConvertRate(recent)
+		t, tu := utils.ConvertBytes(total)
+		return fmt.Sprintf(" %s/s: %9.1f %-5s  %s: %5.1f %s", label, r, ru, tr.Value("total"), t, tu)
+	}
+	for _, name := range names {
+		i := n.ifaces[name]
+		i.recv.Title1 = fmt.Sprintf(" %s  %s  %s", name, i.state, i.addr)
+		i.recv.Title2 = rate("RX", i.recentRecv, i.totalRecv)
+		i.sent.Title1 = rate("TX", i.recentSent, i.totalSent)
+		i.recv.LineColor, i.sent.LineColor = n.RecvColor, n.SentColor
+		i.recv.TitleColor, i.sent.TitleColor = n.TitleColor, n.TitleColor
+		n.Lines = append(n.Lines, i.recv, i.sent)
+	}
+}
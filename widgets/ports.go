@@ -0,0 +1,182 @@
+package widgets
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	tui "github.com/gizak/termui/v3"
+
+	"github.com/xxxserxxx/gotop/v4/devices"
+	ui "github.com/xxxserxxx/gotop/v4/termui"
+	"github.com/xxxserxxx/gotop/v4/utils"
+)
+
+// PortsWidget lists the local listening TCP and UDP ports, and the processes
+// that own them. Ports opened while gotop is running are highlighted for a
+// while, so that new services stand out.
+type PortsWidget struct {
+	*ui.Table
+	updateInterval time.Duration
+	// Ports opened in the last Highlight are drawn in NewColor
+	Highlight time.Duration
+	NewColor  tui.Color
+
+	// opened is when each port was first seen, by protocol, address and
+	// port; ports seen by the first update have the zero time.
+	opened      map[string]time.Time
+	listening   int
+	previousKey string
+}
+
+// NewPortsWidget creates a table of listening ports for host. Remotes don't
+// export their ports, so for them the widget only says so.
+func NewPortsWidget(host string) *PortsWidget {
+	self := &PortsWidget{
+		Table:          ui.NewTable(),
+		updateInterval: time.Second,
+		Highlight:      30 * time.Second,
+		NewColor:       tui.ColorRed,
+	}
+	self.Table.Tr = tr
+	self.Title = tr.Value("widget.label.ports")
+	if host != devices.Local {
+		self.Title = tr.Value("widget.ports.remote", host)
+	}
+	self.Header = []string{
+		tr.Value("widget.conns.proto"), tr.Value("widget.ports.address"), tr.Value("widget.ports.port"),
+		tr.Value("widget.conns.pid"), tr.Value("widget.conns.process"),
+	}
+	self.ShowCursor = true
+	self.ColGap = 2
+	self.UniqueCol = 2
+	self.ColResizer = func() {
+		// Addresses are at most as wide as an IPv6 address
+		addr := utils.MaxInt(15, (self.Inner.Dx()-25)/2)
+		if addr > 39 {
+			addr = 39
+		}
+		self.ColWidths = []int{5, addr, 5, 7, utils.MaxInt(7, self.Inner.Dx()-addr-25)}
+	}
+
+	if passive || host != devices.Local {
+		return self
+	}
+
+	self.update()
+
+	go func() {
+		for range time.NewTicker(self.updateInterval).C {
+			self.Lock()
+			self.update()
+			self.Unlock()
+		}
+	}()
+
+	return self
+}
+
+// EnableMetric exports the number of listening ports.
+func (p *PortsWidget) EnableMetric() {
+	metrics.GetOrCreateGauge(makeName("ports", "listening"), func() float64 {
+		return float64(p.listening)
+	})
+}
+
+func (p *PortsWidget) update() {
+	conns, err := devices.Connections()
+	if err != nil {
+		log.Println(tr.Value("widget.conns.err", err.Error()))
+		return
+	}
+	var rows [][]string
+	for _, c := range conns {
+		if !c.Listening() {
+			continue
+		}
+		row := connRow(c)
+		rows = append(rows, []string{row[0], c.LocalAddr.String(), strconv.Itoa(c.LocalPort), row[4], row[5]})
+	}
+	p.setRows(rows)
+}
+
+// portKey identifies the port of a row.
+func portKey(row []string) string {
+	return strings.Join(row[:3], " ")
+}
+
+// setRows replaces the ports, which are rows of the table, sorts them by
+// port, and highlights those that are new. It's separate from update so that
+// it also works for data from a daemon.
+func (p *PortsWidget) setRows(rows [][]string) {
+	now := time.Now()
+	opened := make(map[string]time.Time, len(rows))
+	valid := rows[:0]
+	for _, row := range rows {
+		if len(row) < 5 {
+			continue
+		}
+		valid = append(valid, row)
+		key := portKey(row)
+		t, ok := p.opened[key]
+		if !ok && p.opened != nil {
+			t = now
+		}
+		opened[key] = t
+	}
+	rows = valid
+	p.opened = opened
+	p.listening = len(rows)
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, _ := strconv.Atoi(rows[i][2])
+		b, _ := strconv.Atoi(rows[j][2])
+		if a != b {
+			return a < b
+		}
+		if rows[i][0] != rows[j][0] {
+			return rows[i][0] < rows[j][0]
+		}
+		return rows[i][1] < rows[j][1]
+	})
+	p.Rows = rows
+	p.colorRows()
+}
+
+// colorRows highlights the ports opened in the last Highlight.
+func (p *PortsWidget) colorRows() {
+	p.RowColors = make(map[int]tui.Color)
+	for i, row := range p.Rows {
+		if t := p.opened[portKey(row)]; !t.IsZero() && time.Since(t) < p.Highlight {
+			p.RowColors[i] = p.NewColor
+		}
+	}
+}
+
+func (p *PortsWidget) HandleEvent(e tui.Event) bool {
+	return navigate(p.Table, e, sequence(&p.previousKey, e.ID))
+}
+
+// Details describes the port under the cursor, and when it was opened.
+func (p *PortsWidget) Details() (string, []string, bool) {
+	if p.SelectedRow < 0 || p.SelectedRow >= len(p.Rows) {
+		return "", nil, false
+	}
+	row := p.Rows[p.SelectedRow]
+	var lines []string
+	for i, h := range p.Header {
+		if i < len(row) {
+			lines = append(lines, fmt.Sprintf("%-14s %s", h+":", row[i]))
+		}
+	}
+	opened := tr.Value("widget.ports.before")
+	if t := p.opened[portKey(row)]; !t.IsZero() {
+		opened = t.Format("2006-01-02 15:04:05")
+	}
+	lines = append(lines, fmt.Sprintf("%-14s %s", tr.Value("widget.ports.opened")+":", opened))
+	return row[0] + " " + row[2], lines, true
+}
@@ -43,12 +43,16 @@ type ProcWidget struct {
 	updateInterval   time.Duration
 	sortMethod       ProcSortMethod
 	filter           string
+	all              []Proc
 	groupedProcs     []Proc
 	ungroupedProcs   []Proc
 	showGroupedProcs bool
+	previousKey      string
 }
 
-func NewProcWidget() *ProcWidget {
+// NewProcWidget creates a process table. Remotes don't export processes, so
+// for any host other than devices.Local the table stays empty.
+func NewProcWidget(host string) *ProcWidget {
 	cpuCount, err := devices.CpuCount()
 	if err != nil {
 		log.Println(tr.Value("error.proc.err.count", err.Error()))
@@ -67,7 +71,7 @@ func NewProcWidget() *ProcWidget {
 		Value: "",
 		UpdateCallback: func(val string) {
 			self.filter = val
-			self.update()
+			self.setProcs(self.all)
 		},
 	}
 	self.Title = tr.Value("widget.proc.label")
@@ -86,6 +90,16 @@ func NewProcWidget() *ProcWidget {
 		self.UniqueCol = 1
 	}
 
+	if host != devices.Local {
+		self.Title = tr.Value("widget.proc.remote", host)
+		self.sortProcs()
+		return self
+	}
+	if passive {
+		self.sortProcs()
+		return self
+	}
+
 	self.update()
 
 	go func() {
@@ -107,8 +121,41 @@ func (proc *ProcWidget) SetEditingFilter(editing bool) {
 	proc.entry.SetEditing(editing)
 }
 
+// HandleEvent handles the filter entry while it's being edited, and
+// otherwise navigation, sorting, grouping, and killing processes.
 func (proc *ProcWidget) HandleEvent(e tui.Event) bool {
-	return proc.entry.HandleEvent(e)
+	if proc.entry.HandleEvent(e) {
+		return true
+	}
+	previous := sequence(&proc.previousKey, e.ID)
+	if navigate(proc.Table, e, previous) {
+		return true
+	}
+	switch e.ID {
+	case "d":
+		if previous == "d" {
+			proc.KillProc("SIGTERM")
+		}
+	case "3":
+		if previous != "d" {
+			return false
+		}
+		proc.KillProc("SIGQUIT")
+	case "9":
+		if previous != "d" {
+			return false
+		}
+		proc.KillProc("SIGKILL")
+	case "t":
+		proc.ToggleShowingGroupedProcs()
+	case "m", "c", "n", "p":
+		proc.ChangeProcSortMethod(ProcSortMethod(e.ID))
+	case "/":
+		proc.SetEditingFilter(true)
+	default:
+		return false
+	}
+	return true
 }
 
 func (proc *ProcWidget) SetRect(x1, y1, x2, y2 int) {
@@ -145,7 +192,12 @@ func (proc *ProcWidget) update() {
 	for i := range procs {
 		procs[i].CPU /= float64(proc.cpuCount)
 	}
+	proc.setProcs(procs)
+}
 
+// setProcs replaces the process list, and filters, groups and sorts it.
+func (proc *ProcWidget) setProcs(procs []Proc) {
+	proc.all = procs
 	procs = proc.filterProcs(procs)
 	proc.ungroupedProcs = procs
 	proc.groupedProcs = groupProcs(procs)
@@ -0,0 +1,237 @@
+package widgets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	tui "github.com/gizak/termui/v3"
+
+	"github.com/xxxserxxx/gotop/v4/devices"
+	ui "github.com/xxxserxxx/gotop/v4/termui"
+	"github.com/xxxserxxx/gotop/v4/utils"
+)
+
+// smartWearWarning is the percentage of a drive's life used at which it's
+// shown in the warning colour.
+const smartWearWarning = 90
+
+// SmartWidget lists the SMART health of the local drives. Drives that gotop
+// can't read, usually because it isn't running as root, are listed as such.
+type SmartWidget struct {
+	*ui.Table
+	updateInterval time.Duration
+	// Rows of drives that are failing, have bad sectors or media errors, or
+	// are nearly worn out are drawn in WarnColor.
+	WarnColor tui.Color
+
+	drives      map[string]*devices.SmartHealth
+	metrics     bool
+	host        string
+	previousKey string
+}
+
+// NewSmartWidget creates a SMART table for host. Remotes don't report SMART
+// data, so for them the widget only says so.
+func NewSmartWidget(host string) *SmartWidget {
+	self := &SmartWidget{
+		Table:          ui.NewTable(),
+		updateInterval: time.Minute,
+		WarnColor:      tui.ColorRed,
+		drives:         make(map[string]*devices.SmartHealth),
+		host:           host,
+	}
+	self.Table.Tr = tr
+	self.Title = tr.Value("widget.label.smart")
+	if host != devices.Local {
+		self.Title = tr.Value("widget.smart.remote", host)
+	}
+	self.Header = []string{
+		tr.Value("widget.smart.drive"), tr.Value("widget.smart.health"), tr.Value("widget.smart.hours"),
+		tr.Value("widget.smart.realloc"), tr.Value("widget.smart.pending"), tr.Value("widget.smart.media"),
+		tr.Value("widget.smart.used"), tr.Value("widget.smart.wear"),
+	}
+	self.ColGap = 2
+	self.ShowCursor = true
+	self.UniqueCol = 0
+	self.ColResizer = func() {
+		self.ColWidths = []int{utils.MaxInt(5, self.Inner.Dx()-59), 10, 6, 7, 7, 5, 4, 4}
+	}
+
+	if passive || host != devices.Local {
+		return self
+	}
+
+	self.update()
+
+	go func() {
+		for range time.NewTicker(self.updateInterval).C {
+			self.Lock()
+			self.update()
+			self.Unlock()
+		}
+	}()
+
+	return self
+}
+
+// EnableMetric exports the SMART data of the drives that can be read,
+// including drives that appear later; values the drive doesn't report are
+// left out.
+func (s *SmartWidget) EnableMetric() {
+	s.metrics = true
+	for name, h := range s.drives {
+		if h.Err == nil {
+			s.enableMetric(name)
+		}
+	}
+}
+
+// enableMetric exports the drive's SMART data. As for disk IO, the gauges
+// look the drive up rather than holding on to it, and read 0 while it's gone
+// or can't be read.
+func (s *SmartWidget) enableMetric(name string) {
+	gauge := func(key string, value func(h *devices.SmartHealth) float64) {
+		metrics.GetOrCreateGauge(makeName("smart", name, key), func() float64 {
+			s.Lock()
+			defer s.Unlock()
+			if h, ok := s.drives[name]; ok && h.Err == nil {
+				return value(h)
+			}
+			return 0
+		})
+	}
+	gauge("failing", func(h *devices.SmartHealth) float64 {
+		if h.Failing {
+			return 1
+		}
+		return 0
+	})
+	h := s.drives[name]
+	for key, v := range map[string]func(h *devices.SmartHealth) int64{
+		"power_on_hours": func(h *devices.SmartHealth) int64 { return h.PowerOnHours },
+		"reallocated":    func(h *devices.SmartHealth) int64 { return h.Reallocated },
+		"pending":        func(h *devices.SmartHealth) int64 { return h.Pending },
+		"media_errors":   func(h *devices.SmartHealth) int64 { return h.MediaErrors },
+		"percent_used":   func(h *devices.SmartHealth) int64 { return h.PercentUsed },
+		"wear":           func(h *devices.SmartHealth) int64 { return h.Wear },
+	} {
+		if v(h) < 0 {
+			continue
+		}
+		vc := v
+		gauge(key, func(h *devices.SmartHealth) float64 {
+			if v := vc(h); v > 0 {
+				return float64(v)
+			}
+			return 0
+		})
+	}
+}
+
+func (s *SmartWidget) update() {
+	var names []string
+	for _, h := range devices.Smart() {
+		d, ok := s.drives[h.Name]
+		// Gauges are registered once a drive can be read, which for
+		// drives that are attached later is after EnableMetric.
+		readable := ok && d.Err == nil
+		if ok {
+			*d = h
+		} else {
+			hc := h
+			s.drives[h.Name] = &hc
+		}
+		if s.metrics && h.Err == nil && !readable {
+			s.enableMetric(h.Name)
+		}
+		names = append(names, h.Name)
+	}
+	s.Rows = make([][]string, len(names))
+	for i, name := range names {
+		h := s.drives[name]
+		s.Rows[i] = []string{
+			name, smartHealth(h),
+			smartValue(h.PowerOnHours, ""), smartValue(h.Reallocated, ""), smartValue(h.Pending, ""),
+			smartValue(h.MediaErrors, ""), smartValue(h.PercentUsed, "%"), smartValue(h.Wear, "%"),
+		}
+	}
+	s.colorRows()
+}
+
+func smartHealth(h *devices.SmartHealth) string {
+	switch {
+	case h.Err != nil && errors.Is(h.Err, os.ErrPermission):
+		return tr.Value("widget.smart.root")
+	case h.Err != nil:
+		return tr.Value("widget.smart.error")
+	case h.Failing:
+		return tr.Value("widget.smart.failing")
+	}
+	return tr.Value("widget.smart.ok")
+}
+
+func smartValue(v int64, unit string) string {
+	if v < 0 {
+		return "-"
+	}
+	return strconv.FormatInt(v, 10) + unit
+}
+
+// colorRows colours the rows of drives that are failing, have reallocated or
+// pending sectors or media errors, or are nearly worn out. It's read from the
+// rows so that it also works for data from a daemon.
+func (s *SmartWidget) colorRows() {
+	s.RowColors = make(map[int]tui.Color)
+	for i, r := range s.Rows {
+		if len(r) < 8 {
+			continue
+		}
+		warn := r[1] == tr.Value("widget.smart.failing")
+		for c := 3; c < 8; c++ {
+			v, err := strconv.Atoi(strings.TrimSuffix(r[c], "%"))
+			if err != nil {
+				continue
+			}
+			if (c < 6 && v > 0) || v >= smartWearWarning {
+				warn = true
+			}
+		}
+		if warn {
+			s.RowColors[i] = s.WarnColor
+		}
+	}
+}
+
+func (s *SmartWidget) HandleEvent(e tui.Event) bool {
+	return navigate(s.Table, e, sequence(&s.previousKey, e.ID))
+}
+
+// Details describes the drive under the cursor.
+func (s *SmartWidget) Details() (string, []string, bool) {
+	if s.SelectedRow < 0 || s.SelectedRow >= len(s.Rows) {
+		return "", nil, false
+	}
+	row := s.Rows[s.SelectedRow]
+	var lines []string
+	// Daemons only provide the table
+	if h, ok := s.drives[row[0]]; ok {
+		lines = append(lines, fmt.Sprintf("%-14s %s", tr.Value("widget.smart.model")+":", h.Model))
+		if h.Type != "" {
+			lines = append(lines, fmt.Sprintf("%-14s %s", tr.Value("widget.smart.type")+":", h.Type))
+		}
+		if h.Err != nil {
+			lines = append(lines, fmt.Sprintf("%-14s %s", tr.Value("widget.smart.reason")+":", h.Err))
+		}
+	}
+	for i, h := range s.Header[1:] {
+		if i+1 < len(row) {
+			lines = append(lines, fmt.Sprintf("%-14s %s", h+":", row[i+1]))
+		}
+	}
+	return row[0], lines, true
+}
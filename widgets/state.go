@@ -0,0 +1,348 @@
+package widgets
+
+import (
+	"time"
+
+	tui "github.com/gizak/termui/v3"
+
+	"github.com/xxxserxxx/gotop/v4/devices"
+	ui "github.com/xxxserxxx/gotop/v4/termui"
+)
+
+// passive is set when the widgets display data collected by a gotop daemon,
+// in which case they don't collect any data themselves.
+var passive bool
+
+// SetPassive turns data collection off (or on) for widgets created afterwards.
+func SetPassive(p bool) {
+	passive = p
+}
+
+// State is a serializable copy of the data displayed by a widget. Only the
+// fields relevant to the widget are set.
+type State struct {
+	Title string
+	// Line graphs
+	Series map[string][]float64
+	Labels map[string]string
+	// Sparklines
+	Sparks []SparkState
+	// Tables
+	Header []string
+	Rows   [][]string
+	// Processes are sent unsorted so that the viewer can sort and filter them
+	Procs []Proc
+	// Temperatures, and the sensors' critical temperatures
+	Temps     map[string]int
+	Criticals map[string]int
+	// Gauges
+	Percent int
+	Label   string
+}
+
+// SparkState is the state of a single sparkline.
+type SparkState struct {
+	Data   []int
+	Title1 string
+	Title2 string
+	Title3 string
+	// Alert is set if Title3 is drawn in the alert colour
+	Alert bool
+}
+
+// Stateful widgets can be copied between a gotop daemon and an attached UI.
+// Snapshot and Restore must be called with the widget locked.
+type Stateful interface {
+	// Snapshot copies the widget's state, including at most points data
+	// points of each graph.
+	Snapshot(points int) State
+	// Restore replaces the widget's data with the state.
+	Restore(State)
+	// Trim discards the data points of each graph that are older than
+	// history.
+	Trim(history time.Duration)
+	Lock()
+	Unlock()
+}
+
+func lineGraphState(lg *ui.LineGraph, points int) State {
+	s := State{
+		Title:  lg.Title,
+		Series: make(map[string][]float64, len(lg.Data)),
+		Labels: make(map[string]string, len(lg.Labels)),
+	}
+	for k, v := range lg.Data {
+		if len(v) > points {
+			v = v[len(v)-points:]
+		}
+		s.Series[k] = append([]float64(nil), v...)
+	}
+	for k, v := range lg.Labels {
+		s.Labels[k] = v
+	}
+	return s
+}
+
+func restoreLineGraph(lg *ui.LineGraph, s State) {
+	lg.Data = s.Series
+	if lg.Data == nil {
+		lg.Data = make(map[string][]float64)
+	}
+	lg.Labels = s.Labels
+	if lg.Labels == nil {
+		lg.Labels = make(map[string]string)
+	}
+}
+
+// historyPoints is the number of data points a graph that's updated every
+// interval has in history; graphs have their own intervals, so they keep
+// different numbers of points.
+func historyPoints(history, interval time.Duration) int {
+	if interval <= 0 || history < interval {
+		return 1
+	}
+	return int(history / interval)
+}
+
+func trimLineGraph(lg *ui.LineGraph, points int) {
+	for k, v := range lg.Data {
+		if len(v) > points {
+			lg.Data[k] = append([]float64(nil), v[len(v)-points:]...)
+		}
+	}
+}
+
+func (cpu *CPUWidget) Snapshot(points int) State { return lineGraphState(cpu.LineGraph, points) }
+func (cpu *CPUWidget) Restore(s State)           { restoreLineGraph(cpu.LineGraph, s) }
+func (cpu *CPUWidget) Trim(history time.Duration) {
+	trimLineGraph(cpu.LineGraph, historyPoints(history, cpu.updateInterval))
+}
+
+func (mem *MemWidget) Snapshot(points int) State { return lineGraphState(mem.LineGraph, points) }
+func (mem *MemWidget) Restore(s State)           { restoreLineGraph(mem.LineGraph, s) }
+func (mem *MemWidget) Trim(history time.Duration) {
+	trimLineGraph(mem.LineGraph, historyPoints(history, mem.updateInterval))
+}
+
+func (b *BatteryWidget) Snapshot(points int) State { return lineGraphState(b.LineGraph, points) }
+func (b *BatteryWidget) Restore(s State)           { restoreLineGraph(b.LineGraph, s) }
+func (b *BatteryWidget) Trim(history time.Duration) {
+	trimLineGraph(b.LineGraph, historyPoints(history, b.updateInterval))
+}
+
+func sparklineState(g *ui.SparklineGroup, points int) State {
+	s := State{Title: g.Title, Sparks: make([]SparkState, len(g.Lines))}
+	for i, l := range g.Lines {
+		d := l.Data
+		if len(d) > points {
+			d = d[len(d)-points:]
+		}
+		s.Sparks[i] = SparkState{
+			Data: append([]int(nil), d...), Title1: l.Title1, Title2: l.Title2, Title3: l.Title3,
+			Alert: l.Title3Color == AlertColor,
+		}
+	}
+	return s
+}
+
+// restoreSparklines replaces the sparklines with those of the state, for
+// widgets whose sparklines come and go with their devices. The lines are
+// given the colours in turn.
+func restoreSparklines(g *ui.SparklineGroup, s State, colors []tui.Color, titleColor tui.Color) {
+	g.Title = s.Title
+	g.Lines = g.Lines[:0]
+	for i, sp := range s.Sparks {
+		l := ui.NewSparkline()
+		l.Data, l.Title1, l.Title2, l.Title3 = sp.Data, sp.Title1, sp.Title2, sp.Title3
+		l.TitleColor = titleColor
+		if sp.Alert {
+			l.Title3Color = AlertColor
+		}
+		if len(colors) > 0 {
+			l.LineColor = colors[i%len(colors)]
+		}
+		g.Lines = append(g.Lines, l)
+	}
+}
+
+func trimSparklines(g *ui.SparklineGroup, points int) {
+	for _, l := range g.Lines {
+		if len(l.Data) > points {
+			l.Data = append([]int(nil), l.Data[len(l.Data)-points:]...)
+		}
+	}
+}
+
+func (net *NetWidget) Snapshot(points int) State { return sparklineState(net.SparklineGroup, points) }
+
+func (net *NetWidget) Restore(s State) {
+	for i, l := range net.Lines {
+		if i < len(s.Sparks) {
+			sp := s.Sparks[i]
+			l.Data, l.Title1, l.Title2, l.Title3 = sp.Data, sp.Title1, sp.Title2, sp.Title3
+			l.Title3Color = tui.ColorClear
+			if sp.Alert {
+				l.Title3Color = AlertColor
+			}
+		}
+	}
+}
+
+func (net *NetWidget) Trim(history time.Duration) {
+	trimSparklines(net.SparklineGroup, historyPoints(history, net.updateInterval))
+}
+
+func (n *NetIfWidget) Snapshot(points int) State { return sparklineState(n.SparklineGroup, points) }
+
+func (n *NetIfWidget) Restore(s State) {
+	restoreSparklines(n.SparklineGroup, s, []tui.Color{n.RecvColor, n.SentColor}, n.TitleColor)
+}
+
+func (n *NetIfWidget) Trim(history time.Duration) {
+	trimSparklines(n.SparklineGroup, historyPoints(history, n.updateInterval))
+}
+
+// The disk IO widget sends only the graph it's showing.
+func (dio *DiskIOWidget) Snapshot(points int) State {
+	return sparklineState(dio.SparklineGroup, points)
+}
+
+func (dio *DiskIOWidget) Restore(s State) {
+	restoreSparklines(dio.SparklineGroup, s, dio.Colors, dio.TitleColor)
+}
+
+func (dio *DiskIOWidget) Trim(history time.Duration) {
+	points := historyPoints(history, dio.updateInterval)
+	for _, d := range dio.disks {
+		for i, h := range d.history {
+			if len(h) > points {
+				d.history[i] = append([]int(nil), h[len(h)-points:]...)
+			}
+		}
+		d.line.Data = d.history[dio.Graph]
+	}
+}
+
+// tableState copies the table's title, header, and rows. They're copied, not
+// shared, because the state is encoded after the widget is unlocked, while
+// the table may be sorted in place.
+func tableState(t *ui.Table) State {
+	s := State{
+		Title:  t.Title,
+		Header: append([]string(nil), t.Header...),
+		Rows:   make([][]string, len(t.Rows)),
+	}
+	for i, row := range t.Rows {
+		s.Rows[i] = append([]string(nil), row...)
+	}
+	return s
+}
+
+func (disk *DiskWidget) Snapshot(int) State {
+	return tableState(disk.Table)
+}
+
+func (disk *DiskWidget) Restore(s State) {
+	disk.Rows = s.Rows
+	disk.colorRows()
+}
+
+func (disk *DiskWidget) Trim(time.Duration) {}
+
+func (s *SmartWidget) Snapshot(int) State {
+	return tableState(s.Table)
+}
+
+func (s *SmartWidget) Restore(st State) {
+	s.Rows = st.Rows
+	s.colorRows()
+}
+
+func (s *SmartWidget) Trim(time.Duration) {}
+
+func (h *HostsWidget) Snapshot(int) State {
+	return tableState(h.Table)
+}
+
+func (h *HostsWidget) Restore(s State) {
+	h.Header, h.Rows = s.Header, s.Rows
+}
+
+func (h *HostsWidget) Trim(time.Duration) {}
+
+func (proc *ProcWidget) Snapshot(int) State {
+	return State{Title: proc.Title, Procs: append([]Proc(nil), proc.all...)}
+}
+
+func (proc *ProcWidget) Restore(s State) {
+	proc.setProcs(s.Procs)
+}
+
+func (proc *ProcWidget) Trim(time.Duration) {}
+
+// Connections are sent unsorted, as rows, so that the viewer can sort and
+// filter them.
+func (c *ConnsWidget) Snapshot(int) State {
+	rows := make([][]string, len(c.all))
+	for i, conn := range c.all {
+		rows[i] = connRow(conn)
+	}
+	return State{Title: c.Title, Rows: rows}
+}
+
+func (c *ConnsWidget) Restore(s State) {
+	conns := make([]devices.Conn, 0, len(s.Rows))
+	for _, row := range s.Rows {
+		if conn, ok := rowConn(row); ok {
+			conns = append(conns, conn)
+		}
+	}
+	c.setConns(conns)
+}
+
+func (c *ConnsWidget) Trim(time.Duration) {}
+
+// Ports are highlighted by the viewer, when it first sees them.
+func (p *PortsWidget) Snapshot(int) State {
+	return tableState(p.Table)
+}
+
+func (p *PortsWidget) Restore(s State) {
+	p.setRows(s.Rows)
+}
+
+func (p *PortsWidget) Trim(time.Duration) {}
+
+func (temp *TempWidget) Snapshot(int) State {
+	s := State{Title: temp.Title, Temps: make(map[string]int, len(temp.Data)), Criticals: make(map[string]int, len(temp.Criticals))}
+	for k, v := range temp.Data {
+		s.Temps[k] = v
+	}
+	for k, v := range temp.Criticals {
+		s.Criticals[k] = v
+	}
+	return s
+}
+
+func (temp *TempWidget) Restore(s State) {
+	temp.Data = s.Temps
+	if temp.Data == nil {
+		temp.Data = make(map[string]int)
+	}
+	temp.Criticals = s.Criticals
+	if temp.Criticals == nil {
+		temp.Criticals = make(map[string]int)
+	}
+}
+
+func (temp *TempWidget) Trim(time.Duration) {}
+
+func (b *BatteryGauge) Snapshot(int) State {
+	return State{Title: b.Title, Percent: b.Percent, Label: b.Label}
+}
+
+func (b *BatteryGauge) Restore(s State) {
+	b.Percent, b.Label = s.Percent, s.Label
+}
+
+func (b *BatteryGauge) Trim(time.Duration) {}
@@ -4,17 +4,30 @@ import (
 	"image"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	ui "github.com/gizak/termui/v3"
+
+	"github.com/xxxserxxx/gotop/v4/alerts"
+	"github.com/xxxserxxx/gotop/v4/devices"
 )
 
 type StatusBar struct {
 	ui.Block
+	// Host is the name of the remote being viewed, or devices.Local
+	Host string
+	// Alerts, if set, provides the firing alerts shown after the host name
+	Alerts *alerts.Engine
+	// Pages are the names of the layout's pages, which are shown as tabs if
+	// there's more than one; Page is the current one.
+	Pages []string
+	Page  int
 }
 
 func NewStatusBar() *StatusBar {
-	self := &StatusBar{*ui.NewBlock()}
+	self := &StatusBar{Block: *ui.NewBlock()}
 	self.Border = false
 	return self
 }
@@ -27,6 +40,9 @@ func (sb *StatusBar) Draw(buf *ui.Buffer) {
 		log.Printf(tr.Value("error.nohostname", err.Error()))
 		return
 	}
+	if sb.Host != devices.Local {
+		hostname = tr.Value("widget.status.remote", sb.Host)
+	}
 	buf.SetString(
 		hostname,
 		ui.Theme.Default,
@@ -35,15 +51,36 @@ func (sb *StatusBar) Draw(buf *ui.Buffer) {
 
 	currentTime := time.Now()
 	formattedTime := currentTime.Format("15:04:05")
+	timeX := sb.Inner.Min.X + (sb.Inner.Dx() / 2) - len(formattedTime)/2
 	buf.SetString(
 		formattedTime,
 		ui.Theme.Default,
-		image.Pt(
-			sb.Inner.Min.X+(sb.Inner.Dx()/2)-len(formattedTime)/2,
-			sb.Inner.Min.Y+(sb.Inner.Dy()/2),
-		),
+		image.Pt(timeX, sb.Inner.Min.Y+(sb.Inner.Dy()/2)),
 	)
 
+	if sb.Alerts != nil {
+		var firing []string
+		for _, a := range sb.Alerts.Firing() {
+			firing = append(firing, a.Rule.Name+" "+a.Metric())
+		}
+		if len(firing) > 0 {
+			x := sb.Inner.Min.X + len(hostname) + 2
+			text := []rune(tr.Value("widget.status.alerts", strings.Join(firing, ", ")))
+			if max := timeX - x - 1; len(text) > max && max > 0 {
+				text = append(text[:max-1], '…')
+			}
+			if x+len(text) < timeX {
+				buf.SetString(
+					string(text),
+					ui.NewStyle(AlertColor, ui.ColorClear, ui.ModifierBold),
+					image.Pt(x, sb.Inner.Min.Y+(sb.Inner.Dy()/2)),
+				)
+			}
+		}
+	}
+
+	sb.drawPages(buf, timeX+len(formattedTime)+2, sb.Inner.Max.X-7)
+
 	// i, e := host.Info()
 	// i.Uptime // Number of seconds since boot
 	buf.SetString(
@@ -55,3 +92,37 @@ func (sb *StatusBar) Draw(buf *ui.Buffer) {
 		),
 	)
 }
+
+// drawPages draws the page tabs, right aligned in the space between x1 and x2.
+// Page names are left out if they don't fit.
+func (sb *StatusBar) drawPages(buf *ui.Buffer, x1, x2 int) {
+	if len(sb.Pages) < 2 {
+		return
+	}
+	tabs := make([]string, len(sb.Pages))
+	for _, named := range []bool{true, false} {
+		width := len(tabs) - 1
+		for i, name := range sb.Pages {
+			tabs[i] = " " + strconv.Itoa(i+1)
+			if named && name != "" {
+				tabs[i] += ":" + name
+			}
+			tabs[i] += " "
+			width += len([]rune(tabs[i]))
+		}
+		if x2-width < x1 {
+			continue
+		}
+		x := x2 - width
+		y := sb.Inner.Min.Y + (sb.Inner.Dy() / 2)
+		for i, tab := range tabs {
+			style := ui.Theme.Default
+			if i == sb.Page {
+				style = ui.NewStyle(style.Fg, style.Bg, ui.ModifierReverse)
+			}
+			buf.SetString(tab, style, image.Pt(x, y))
+			x += len([]rune(tab)) + 1
+		}
+		return
+	}
+}
@@ -28,21 +28,41 @@ type TempWidget struct {
 	TempLowColor   ui.Color
 	TempHighColor  ui.Color
 	TempScale      TempScale
-	temps          map[string]float64
+	// Criticals holds the critical temperature of each sensor that reports
+	// one, in the displayed scale
+	Criticals map[string]int
+	temps     map[string]float64
+	host      string
+	critical  hysteresis
+	// filter holds glob patterns of the sensors to show, as for
+	// utils.GlobFilter
+	filter []string
 }
 
-func NewTempWidget(tempScale TempScale, filter []string) *TempWidget {
+// NewTempWidget creates a temperature widget for host, which is either the
+// name of a remote or devices.Local. The filter is glob patterns of the
+// sensors to show, or with a !, to hide; without one, the default local
+// sensors, or all of a remote's, are shown.
+func NewTempWidget(tempScale TempScale, filter []string, host string) *TempWidget {
 	self := &TempWidget{
 		Block:          ui.NewBlock(),
 		updateInterval: time.Second * 5,
 		Data:           make(map[string]int),
 		TempThreshold:  80,
 		TempScale:      tempScale,
+		Criticals:      make(map[string]int),
+		host:           host,
+		critical:       make(hysteresis),
+		filter:         filter,
 	}
 	self.Title = tr.Value("widget.label.temp")
-	if len(filter) > 0 {
-		for _, t := range filter {
-			self.Data[t] = 0
+	if host != devices.Local {
+		self.updateRemote()
+	} else if len(filter) > 0 {
+		for _, t := range devices.Devices(devices.Temperatures, true) {
+			if utils.GlobFilter(filter, t) {
+				self.Data[t] = 0
+			}
 		}
 	} else {
 		for _, t := range devices.Devices(devices.Temperatures, false) {
@@ -54,6 +74,10 @@ func NewTempWidget(tempScale TempScale, filter []string) *TempWidget {
 		self.TempThreshold = utils.CelsiusToFahrenheit(self.TempThreshold)
 	}
 
+	if passive {
+		return self
+	}
+
 	self.update()
 
 	go func() {
@@ -71,7 +95,7 @@ func (temp *TempWidget) EnableMetric() {
 	temp.temps = make(map[string]float64)
 	for k, _ := range temp.Data {
 		kc := k
-		metrics.NewGauge(makeName("temp", k), func() float64 {
+		metrics.GetOrCreateGauge(makeName("temp", k), func() float64 {
 			return float64(temp.Data[kc])
 		})
 	}
@@ -115,8 +139,23 @@ func (temp *TempWidget) Draw(buf *ui.Buffer) {
 	}
 }
 
+// updateRemote replaces the data with the remote's filtered sensors, which
+// may change between polls.
+func (temp *TempWidget) updateRemote() {
+	devices.HostTemps(temp.host, temp.Data)
+	for name := range temp.Data {
+		if !utils.GlobFilter(temp.filter, name) {
+			delete(temp.Data, name)
+		}
+	}
+}
+
 func (temp *TempWidget) update() {
-	devices.UpdateTemps(temp.Data)
+	if temp.host != devices.Local {
+		temp.updateRemote()
+	} else {
+		devices.HostTemps(temp.host, temp.Data)
+	}
 	for name, val := range temp.Data {
 		if temp.TempScale == Fahrenheit {
 			temp.Data[name] = utils.CelsiusToFahrenheit(val)
@@ -124,4 +163,13 @@ func (temp *TempWidget) update() {
 			temp.Data[name] = val
 		}
 	}
+	if temp.host != devices.Local {
+		return
+	}
+	for name, val := range devices.TempCriticals() {
+		if temp.TempScale == Fahrenheit {
+			val = utils.CelsiusToFahrenheit(val)
+		}
+		temp.Criticals[name] = val
+	}
 }